@@ -0,0 +1,52 @@
+package scan
+
+// BaseRows holds the methods of [Rows] that most drivers already implement
+// with the same signature
+type BaseRows interface {
+	Scan(...any) error
+	Next() bool
+	Err() error
+}
+
+// RowsAdapter implements [Rows] by using [BaseRows] for the common methods
+// and the given functions for the others.
+// It is meant to make it easier to support new drivers and can be embedded
+// to add more methods.
+type RowsAdapter struct {
+	BaseRows
+	columns func() ([]string, error)
+	close   func() error
+}
+
+// AdaptRows creates a [RowsAdapter] from the driver's rows
+// and functions for the methods which differ from [Rows].
+//
+// If close is nil, the Close method of the base is used if it has the
+// signature `Close() error`. Otherwise closing does nothing.
+func AdaptRows(base BaseRows, columns func() ([]string, error), close func() error) RowsAdapter {
+	if close == nil {
+		if closer, ok := base.(interface{ Close() error }); ok {
+			close = closer.Close
+		}
+	}
+
+	return RowsAdapter{
+		BaseRows: base,
+		columns:  columns,
+		close:    close,
+	}
+}
+
+// Columns returns the column names
+func (r RowsAdapter) Columns() ([]string, error) {
+	return r.columns()
+}
+
+// Close closes the underlying rows
+func (r RowsAdapter) Close() error {
+	if r.close == nil {
+		return nil
+	}
+
+	return r.close()
+}
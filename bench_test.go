@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -18,6 +19,10 @@ import (
 var (
 	db       *sql.DB
 	dataSize = 100
+
+	// the large table is only created when the benchmarks that need it are run
+	largeDataSize = 10000
+	largeDataOnce sync.Once
 )
 
 func TestMain(m *testing.M) {
@@ -60,6 +65,39 @@ func BenchmarkScanAll(b *testing.B) {
 	}
 }
 
+func BenchmarkScanAllLarge(b *testing.B) {
+	benchmarkScanAllLarge(b, 0)
+}
+
+func BenchmarkScanAllLargeCap(b *testing.B) {
+	benchmarkScanAllLarge(b, largeDataSize)
+}
+
+func benchmarkScanAllLarge(b *testing.B, capHint int) {
+	b.StopTimer()
+	ctx := context.Background()
+
+	largeDataOnce.Do(func() {
+		if err := prepareLargeData(ctx); err != nil {
+			panic(err)
+		}
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		rows, err := db.Query("SELECT|large||")
+		if err != nil {
+			panic(err)
+		}
+		b.StartTimer()
+		if _, err := AllFromRowsCap(ctx, SingleColumnMapper[int64], rows, capHint); err != nil {
+			panic(err)
+		}
+		rows.Close()
+	}
+}
+
 func BenchmarkScanOne(b *testing.B) {
 	b.StopTimer()
 	ctx := context.Background()
@@ -78,6 +116,47 @@ func BenchmarkScanOne(b *testing.B) {
 	}
 }
 
+func BenchmarkCursor(b *testing.B) {
+	benchmarkCursor(b, func(ctx context.Context, rows Rows) (ICursor[Userss], error) {
+		return CursorFromRows(ctx, StructMapper[Userss](), rows)
+	})
+}
+
+func BenchmarkCursorPrefetch(b *testing.B) {
+	benchmarkCursor(b, func(ctx context.Context, rows Rows) (ICursor[Userss], error) {
+		return CursorPrefetchFromRows(ctx, StructMapper[Userss](), rows, 16)
+	})
+}
+
+func benchmarkCursor(b *testing.B, newCursor func(context.Context, Rows) (ICursor[Userss], error)) {
+	b.StopTimer()
+	ctx := context.Background()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		rows, err := db.Query("SELECT|user||")
+		if err != nil {
+			panic(err)
+		}
+		b.StartTimer()
+
+		c, err := newCursor(ctx, rows)
+		if err != nil {
+			panic(err)
+		}
+
+		for c.Next() {
+			if _, err := c.Get(); err != nil {
+				panic(err)
+			}
+		}
+
+		if err := c.Close(); err != nil {
+			panic(err)
+		}
+	}
+}
+
 func prepareData(ctx context.Context) error {
 	create := "CREATE|user|id=int64,username=string,password=string"
 	create += ",email=string,mobile_phone=string,company=string,avatar_url=string"
@@ -108,6 +187,20 @@ func prepareData(ctx context.Context) error {
 	return nil
 }
 
+func prepareLargeData(ctx context.Context) error {
+	if _, err := db.ExecContext(ctx, "CREATE|large|id=int64"); err != nil {
+		return err
+	}
+
+	for i := 0; i < largeDataSize; i++ {
+		if _, err := db.ExecContext(ctx, "INSERT|large|id=?", i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 type Userss struct {
 	ID           int       `db:"id"`
 	UserName     string    `db:"username"`
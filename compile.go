@@ -0,0 +1,95 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CompiledMapper holds the mapping functions of a [Mapper] that has already
+// been generated for a known set of columns.
+// Use [CompiledMapper.Mapper] to use it with the scanning functions
+type CompiledMapper[T any] struct {
+	cols   []string
+	before BeforeFunc
+	after  func(any) (T, error)
+}
+
+// Compile generates the mapping functions of the mapper once for the given columns.
+// This is useful when the same query is run many times, since the work done by
+// the mapper generator is not repeated for every query.
+//
+// The before function is run once against the columns, and any mapping error
+// it returns, or any column left without a destination, is returned by Compile.
+//
+// Since the same functions are used by every query, the mapper must be safe
+// for concurrent use if the compiled mapper is used concurrently
+func Compile[T any](m Mapper[T], columns []string) (CompiledMapper[T], error) {
+	return CompileContext(context.Background(), m, columns)
+}
+
+// CompileContext works like [Compile] but generates the mapper with the given context,
+// so settings read from the context (such as [CtxKeyAllowUnknownColumns]) are fixed when compiling
+func CompileContext[T any](ctx context.Context, m Mapper[T], columns []string) (CompiledMapper[T], error) {
+	if m == nil {
+		return CompiledMapper[T]{}, errors.New("cannot compile a nil mapper")
+	}
+
+	c := make([]string, len(columns))
+	copy(c, columns)
+
+	before, after := m(ctx, c)
+
+	v := newRow(ctx, c)
+	if _, err := before(v); err != nil {
+		return CompiledMapper[T]{}, err
+	}
+
+	// Only check the scheduled scans, unknown columns are warned about
+	// when the compiled mapper is used
+	v.unknownWarner = nil
+	if _, err := v.createTargets(); err != nil {
+		return CompiledMapper[T]{}, err
+	}
+
+	return CompiledMapper[T]{
+		cols:   c,
+		before: before,
+		after:  after,
+	}, nil
+}
+
+// Columns returns a copy of the columns the mapper was compiled for
+func (c CompiledMapper[T]) Columns() []string {
+	cols := make([]string, len(c.cols))
+	copy(cols, c.cols)
+	return cols
+}
+
+// Mapper returns a [Mapper] that reuses the compiled mapping functions.
+// It returns an error if the columns of the result do not match the
+// columns it was compiled for
+func (c CompiledMapper[T]) Mapper() Mapper[T] {
+	return func(ctx context.Context, columns cols) (BeforeFunc, func(any) (T, error)) {
+		if !sameColumns(c.cols, columns) {
+			err := fmt.Errorf("compiled for columns %v but got %v", c.cols, columns)
+			return ErrorMapper[T](err, "compiled columns mismatch")
+		}
+
+		return c.before, c.after
+	}
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
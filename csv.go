@@ -0,0 +1,86 @@
+package scan
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+)
+
+// EncodeCSV runs the query and writes the results to w as CSV.
+// The first record is a header with the column names, followed by one record per row.
+// NULL values are written as empty fields.
+// Use [EncodeCSVWith] to change how the values are formatted
+func EncodeCSV(ctx context.Context, exec Queryer, w io.Writer, query string, args ...any) error {
+	return EncodeCSVWith(ctx, exec, w, formatCSVValue, query, args...)
+}
+
+// EncodeCSVWith works like [EncodeCSV] but uses format to convert the values to strings.
+// format is not called for NULL values which are always written as empty fields
+func EncodeCSVWith(ctx context.Context, exec Queryer, w io.Writer, format func(any) string, query string, args ...any) error {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	c, err := CursorFromRows(withQueryArgs(ctx, args), SliceMapper[any], rows)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for c.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		vals, err := c.Get()
+		if err != nil {
+			return err
+		}
+
+		for i, val := range vals {
+			if val == nil {
+				record[i] = ""
+				continue
+			}
+
+			record[i] = format(val)
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Err(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatCSVValue is the default formatter used by [EncodeCSV]
+func formatCSVValue(val any) string {
+	switch val := val.(type) {
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -1,5 +1,10 @@
 package scan
 
+import (
+	"errors"
+	"sync"
+)
+
 type ICursor[T any] interface {
 	// Close the underlying rows
 	Close() error
@@ -32,3 +37,157 @@ func (c *cursor[T]) Next() bool {
 func (c *cursor[T]) Get() (T, error) {
 	return scanOneRow(c.v, c.before, c.after)
 }
+
+// BufferedCursor is an [ICursor] that holds all the rows in memory
+// so it can be iterated over multiple times
+type BufferedCursor[T any] interface {
+	ICursor[T]
+	// Reset moves the cursor back to before the first row
+	Reset()
+	// Len returns the total number of rows
+	Len() int
+}
+
+type bufferedCursor[T any] struct {
+	rows []T
+	pos  int
+}
+
+func (c *bufferedCursor[T]) Close() error {
+	return nil
+}
+
+func (c *bufferedCursor[T]) Err() error {
+	return nil
+}
+
+func (c *bufferedCursor[T]) Next() bool {
+	if c.pos >= len(c.rows) {
+		return false
+	}
+
+	c.pos++
+	return c.pos < len(c.rows)
+}
+
+func (c *bufferedCursor[T]) Get() (T, error) {
+	if c.pos < 0 || c.pos >= len(c.rows) {
+		var t T
+		return t, errors.New("cursor is not on a row")
+	}
+
+	return c.rows[c.pos], nil
+}
+
+func (c *bufferedCursor[T]) Reset() {
+	c.pos = -1
+}
+
+func (c *bufferedCursor[T]) Len() int {
+	return len(c.rows)
+}
+
+type prefetched[T any] struct {
+	val T
+	err error
+}
+
+// prefetchCursor is an [ICursor] that scans the rows in a goroutine
+// ahead of the consumer. See [CursorPrefetch]
+type prefetchCursor[T any] struct {
+	results <-chan prefetched[T]
+	current prefetched[T]
+	closed  bool
+
+	// done stops the goroutine and stopped is closed once it has returned
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	cancel    func()
+
+	// set by the goroutine before the results are closed
+	mu       sync.Mutex
+	err      error
+	closeErr error
+}
+
+// prefetch scans the rows into the results until there are no more rows or the cursor is closed
+func (c *prefetchCursor[T]) prefetch(v *Row, before func(*Row) (any, error), after func(any) (T, error), results chan<- prefetched[T]) {
+	defer close(c.stopped)
+	defer close(results)
+
+	for !c.isDone() && v.r.Next() {
+		val, err := scanOneRow(v, before, after)
+
+		select {
+		case results <- prefetched[T]{val: val, err: err}:
+		case <-c.done:
+			c.finish(nil, v.r.Close())
+			return
+		}
+	}
+
+	// Errors from cancelling the rows on Close are not reported
+	if c.isDone() {
+		c.finish(nil, v.r.Close())
+		return
+	}
+
+	c.finish(v.r.Err(), v.r.Close())
+}
+
+// isDone reports whether the cursor has been closed, so no more rows should be read
+func (c *prefetchCursor[T]) isDone() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *prefetchCursor[T]) finish(err, closeErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err, c.closeErr = err, closeErr
+}
+
+func (c *prefetchCursor[T]) Close() error {
+	c.closeOnce.Do(func() {
+		c.closed = true
+		close(c.done)
+		c.cancel()
+	})
+	<-c.stopped
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closeErr
+}
+
+func (c *prefetchCursor[T]) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *prefetchCursor[T]) Next() bool {
+	if c.closed {
+		return false
+	}
+
+	r, ok := <-c.results
+	if !ok {
+		return false
+	}
+
+	c.current = r
+	return true
+}
+
+func (c *prefetchCursor[T]) Get() (T, error) {
+	return c.current.val, c.current.err
+}
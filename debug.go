@@ -8,20 +8,43 @@ import (
 )
 
 func Debug(q Queryer, w io.Writer) Queryer {
+	return DebugRedacting(q, w, nil)
+}
+
+// DebugRedacting works like [Debug] but every arg is passed through redact
+// before it is printed. This makes it possible to mask secrets such as passwords.
+// The args passed to the underlying [Queryer] are not modified
+//
+//	exec := scan.DebugRedacting(db, nil, func(i int, v any) any {
+//	    if i == 1 {
+//	        return "***"
+//	    }
+//	    return v
+//	})
+func DebugRedacting(q Queryer, w io.Writer, redact func(i int, v any) any) Queryer {
 	if w == nil {
 		w = os.Stdout
 	}
 
-	return debugQueryer{w: w, q: q}
+	return debugQueryer{w: w, q: q, redact: redact}
 }
 
 type debugQueryer struct {
-	w io.Writer
-	q Queryer
+	w      io.Writer
+	q      Queryer
+	redact func(i int, v any) any
 }
 
 func (d debugQueryer) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	printed := args
+	if d.redact != nil {
+		printed = make([]any, len(args))
+		for i, arg := range args {
+			printed[i] = d.redact(i, arg)
+		}
+	}
+
 	fmt.Fprintln(d.w, query)
-	fmt.Fprintln(d.w, []any(args))
+	fmt.Fprintln(d.w, printed)
 	return d.q.QueryContext(ctx, query, args...)
 }
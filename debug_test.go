@@ -77,3 +77,41 @@ func TestDebugQueryer(t *testing.T) {
 		}
 	}
 }
+
+type argsQueryer struct {
+	args *[]any
+}
+
+func (a argsQueryer) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	*a.args = args
+	return nil, nil
+}
+
+func TestDebugQueryerRedacting(t *testing.T) {
+	dest := &bytes.Buffer{}
+	var received []any
+
+	exec := DebugRedacting(argsQueryer{args: &received}, dest, func(i int, v any) any {
+		if i == 1 {
+			return "***"
+		}
+		return v
+	})
+
+	args := []any{"user", "secret", 3}
+	if _, err := exec.QueryContext(context.Background(), "A QUERY", args...); err != nil {
+		t.Fatal("error running QueryContext")
+	}
+
+	if expected := "A QUERY\n[user *** 3]\n"; dest.String() != expected {
+		t.Fatalf("wrong debug output.\nExpected: %q\nGot: %q", expected, dest.String())
+	}
+
+	if fmt.Sprint(received) != "[user secret 3]" {
+		t.Fatalf("args passed to the queryer were modified: %v", received)
+	}
+
+	if fmt.Sprint(args) != "[user secret 3]" {
+		t.Fatalf("args were modified: %v", args)
+	}
+}
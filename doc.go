@@ -0,0 +1,14 @@
+// Package scan provides the ability to use database/sql/rows to scan datasets
+// directly to any defined structure.
+//
+// The query functions such as [One], [All] and [Each] run the query with a [Queryer]
+// and map every row with a [Mapper]. Each of them has a FromRows variant, such as
+// [AllFromRows], that works with an existing [Rows] instead.
+//
+// # Query arguments
+//
+// The query functions make the query arguments available to the mapper, which is
+// what [WithArgField] uses to set a field from an argument. The FromRows functions
+// do not know the arguments of the query that produced the rows, so a struct mapper
+// with [WithArgField] returns an error when used with them.
+package scan
@@ -3,6 +3,8 @@ package scan
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 )
 
 // One scans a single row from the query and maps it to T using a [Queryer]
@@ -11,19 +13,25 @@ func One[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, ar
 
 	rows, err := exec.QueryContext(ctx, query, args...)
 	if err != nil {
+		onComplete(ctx)(0, err)
 		return t, err
 	}
 	defer rows.Close()
 
-	return OneFromRows(ctx, m, rows)
+	return OneFromRows(withQueryArgs(ctx, args), m, rows)
 }
 
 // OneFromRows scans a single row from the given [Rows] result and maps it to T using a [Queryer]
-func OneFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) (T, error) {
-	var t T
+func OneFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) (t T, err error) {
+	defer func() {
+		var n int
+		if err == nil {
+			n = 1
+		}
+		onComplete(ctx)(n, err)
+	}()
 
-	allowUnknown, _ := ctx.Value(CtxKeyAllowUnknownColumns).(bool)
-	v, err := wrapRows(rows, allowUnknown)
+	v, err := wrapRows(ctx, rows)
 	if err != nil {
 		return t, err
 	}
@@ -45,21 +53,98 @@ func OneFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) (T, error)
 	return t, rows.Err()
 }
 
+// OneOrZero works like [One] but returns false instead of [sql.ErrNoRows]
+// when the query returns no rows
+func OneOrZero[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) (T, bool, error) {
+	var t T
+
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		onComplete(ctx)(0, err)
+		return t, false, err
+	}
+	defer rows.Close()
+
+	return OneOrZeroFromRows(withQueryArgs(ctx, args), m, rows)
+}
+
+// OneOrZeroFromRows works like [OneFromRows] but returns false instead of [sql.ErrNoRows]
+// when there are no rows
+func OneOrZeroFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) (T, bool, error) {
+	t, err := OneFromRows(ctx, m, rows)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, false, nil
+	}
+	if err != nil {
+		return t, false, err
+	}
+
+	return t, true, nil
+}
+
+// ScanRow maps the current row of the given [Rows] to T without advancing it.
+// Next must already have been called, this makes it possible to
+// mix manual iteration with mapping.
+//
+// The mapping functions are generated on every call, so prefer [CursorFromRows]
+// when mapping every row
+func ScanRow[T any](ctx context.Context, m Mapper[T], rows Rows) (T, error) {
+	var t T
+
+	v, err := wrapRows(ctx, rows)
+	if err != nil {
+		return t, err
+	}
+
+	before, after := m(ctx, v.columnsCopy())
+
+	return scanOneRow(v, before, after)
+}
+
+// Validate checks that the mapper can map a row with the given columns without
+// running a query. The mapper's before function is run against a row with the columns
+// and the error that scanning the row would return is returned, such as a column
+// with no destination or a scan scheduled for an unknown column.
+//
+// Nothing is scanned, so errors that only happen in the after function are not detected.
+// This is useful to check that a mapper matches a schema in tests or at startup
+func Validate[T any](ctx context.Context, m Mapper[T], columns []string) error {
+	v := newRow(ctx, columns)
+
+	before, _ := m(ctx, v.columnsCopy())
+	if _, err := before(v); err != nil {
+		return err
+	}
+
+	_, err := v.createTargets()
+	return err
+}
+
 // All scans all rows from the query and returns a slice []T of all rows using a [Queryer]
 func All[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) ([]T, error) {
 	rows, err := exec.QueryContext(ctx, query, args...)
 	if err != nil {
+		onComplete(ctx)(0, err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	return AllFromRows(ctx, m, rows)
+	return AllFromRows(withQueryArgs(ctx, args), m, rows)
 }
 
 // AllFromRows scans all rows from the given [Rows] and returns a slice []T of all rows using a [Queryer]
 func AllFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) ([]T, error) {
-	allowUnknown, _ := ctx.Value(CtxKeyAllowUnknownColumns).(bool)
-	v, err := wrapRows(rows, allowUnknown)
+	return AllFromRowsCap(ctx, m, rows, 0)
+}
+
+// AllFromRowsCap works like [AllFromRows] but preallocates the returned slice
+// with the given capacity. This reduces allocations when the number of rows
+// is roughly known in advance
+func AllFromRowsCap[T any](ctx context.Context, m Mapper[T], rows Rows, capHint int) (_ []T, err error) {
+	var n int
+	defer func() { onComplete(ctx)(n, err) }()
+
+	v, err := wrapRows(ctx, rows)
 	if err != nil {
 		return nil, err
 	}
@@ -67,18 +152,312 @@ func AllFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) ([]T, error
 	before, after := m(ctx, v.columnsCopy())
 
 	var results []T
+	if capHint > 0 {
+		results = make([]T, 0, capHint)
+	}
+
 	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		one, err := scanOneRow(v, before, after)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, one)
+		n++
+	}
+
+	return results, rows.Err()
+}
+
+// AllWhile scans the rows from the query and returns them until predicate returns false
+// for a row. That row is not included and the remaining rows are not scanned.
+// Like [All], the error from the rows is still returned
+func AllWhile[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, predicate func(T) bool, args ...any) ([]T, error) {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		onComplete(ctx)(0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return AllWhileFromRows(withQueryArgs(ctx, args), m, rows, predicate)
+}
+
+// AllWhileFromRows works like [AllWhile] but scans the rows from the given [Rows]
+func AllWhileFromRows[T any](ctx context.Context, m Mapper[T], rows Rows, predicate func(T) bool) (_ []T, err error) {
+	var n int
+	defer func() { onComplete(ctx)(n, err) }()
+
+	v, err := wrapRows(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	before, after := m(ctx, v.columnsCopy())
+
+	var results []T
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		one, err := scanOneRow(v, before, after)
 		if err != nil {
 			return nil, err
 		}
 
+		if !predicate(one) {
+			break
+		}
+
+		results = append(results, one)
+		n++
+	}
+
+	return results, rows.Err()
+}
+
+// RowError is the error for a single row returned by [AllTolerant]
+type RowError struct {
+	// the zero-based index of the row
+	Index int
+	Err   error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Index, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// AllTolerant works like [All] but does not stop at rows that fail to scan or map.
+// The rows that were scanned successfully are returned together with a [*RowError]
+// for every row that failed. The last error is only for failures of the whole query,
+// such as an error running the query, an invalid mapper, a column without a destination
+// or an error from the rows.
+//
+// Every failed row keeps its error, so a query with many bad rows holds
+// all the errors in memory together with the scanned rows
+func AllTolerant[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) ([]T, []error, error) {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		onComplete(ctx)(0, err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	return AllTolerantFromRows(withQueryArgs(ctx, args), m, rows)
+}
+
+// AllTolerantFromRows works like [AllTolerant] but scans the rows from the given [Rows]
+func AllTolerantFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) (_ []T, rowErrs []error, err error) {
+	var n int
+	defer func() { onComplete(ctx)(n, err) }()
+
+	v, err := wrapRows(ctx, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before, after := m(ctx, v.columnsCopy())
+
+	var results []T
+	for i := 0; rows.Next(); i++ {
+		if err := ctx.Err(); err != nil {
+			return results, rowErrs, err
+		}
+
+		v.scanned = nil
+
+		// Errors before scanning do not depend on the values
+		// of the row, so they would fail every row
+		link, err := before(v)
+		if err != nil {
+			return results, rowErrs, err
+		}
+
+		targets, err := v.createTargets()
+		if err != nil {
+			return results, rowErrs, err
+		}
+
+		if err := v.scanTargets(targets); err != nil {
+			rowErrs = append(rowErrs, &RowError{Index: i, Err: err})
+			continue
+		}
+
+		one, err := after(link)
+		if err != nil {
+			rowErrs = append(rowErrs, &RowError{Index: i, Err: err})
+			continue
+		}
+
 		results = append(results, one)
+		n++
+	}
+
+	return results, rowErrs, rows.Err()
+}
+
+// allMapFromRows scans all rows from the given [Rows] into a map using the value of keyCol as the key.
+// If multiple rows have the same key, the last one is kept
+func allMapFromRows[K comparable, T any](ctx context.Context, m Mapper[T], keyCol string, rows Rows) (_ map[K]T, err error) {
+	var n int
+	defer func() { onComplete(ctx)(n, err) }()
+
+	v, err := wrapRows(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	before, after := keyedMapper[K](keyCol, m)(ctx, v.columnsCopy())
+
+	results := make(map[K]T)
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		one, err := scanOneRow(v, before, after)
+		if err != nil {
+			return nil, err
+		}
+
+		results[one.key] = one.val
+		n++
+	}
+
+	return results, rows.Err()
+}
+
+// AllMap scans the key and value columns of all rows from the query into a map.
+// This is useful for key/value tables.
+// If multiple rows have the same key, the last one is kept.
+// Numbers are only converted to K if they fit, and only strings and byte slices
+// are converted to a string K
+//
+//	// map[string]string{"theme": "dark", ...}
+//	settings, err := scan.AllMap[string, string](ctx, exec, "key", "value", query)
+func AllMap[K comparable, V any](ctx context.Context, exec Queryer, keyCol, valCol string, query string, args ...any) (map[K]V, error) {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		onComplete(ctx)(0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return AllMapFromRows[K, V](withQueryArgs(ctx, args), keyCol, valCol, rows)
+}
+
+// AllMapFromRows scans the key and value columns of all rows from the given [Rows] into a map.
+// If multiple rows have the same key, the last one is kept
+func AllMapFromRows[K comparable, V any](ctx context.Context, keyCol, valCol string, rows Rows) (map[K]V, error) {
+	return allMapFromRows[K](ctx, ColumnMapper[V](valCol), keyCol, rows)
+}
+
+// AllSet scans the single column of every row from the query into a set.
+// Duplicate values are only kept once, which is useful for queries such as
+// SELECT DISTINCT. Like [SingleColumnMapper], the query must return exactly one column
+//
+//	// map[string]struct{}{"admin": {}, "user": {}}
+//	roles, err := scan.AllSet[string](ctx, exec, "SELECT role FROM users")
+func AllSet[T comparable](ctx context.Context, exec Queryer, query string, args ...any) (map[T]struct{}, error) {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		onComplete(ctx)(0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return AllSetFromRows[T](withQueryArgs(ctx, args), rows)
+}
+
+// AllSetFromRows scans the single column of every row from the given [Rows] into a set
+func AllSetFromRows[T comparable](ctx context.Context, rows Rows) (_ map[T]struct{}, err error) {
+	var n int
+	defer func() { onComplete(ctx)(n, err) }()
+
+	v, err := wrapRows(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	before, after := SingleColumnMapper[T](ctx, v.columnsCopy())
+
+	results := make(map[T]struct{})
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		one, err := scanOneRow(v, before, after)
+		if err != nil {
+			return nil, err
+		}
+
+		results[one] = struct{}{}
+		n++
 	}
 
 	return results, rows.Err()
 }
 
+// AllWithRowsAffected works like [All] but also returns the number of rows affected by the query.
+// This is useful for queries with a RETURNING clause.
+//
+// The count is only available if the [Rows] implement [RowsAffecter].
+// Many drivers do not provide this, in which case the number of returned rows is used
+func AllWithRowsAffected[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) (all []T, affected int64, err error) {
+	// The hook is called once here, after the rows are closed and the count is read
+	complete := onComplete(ctx)
+	defer func() { complete(len(all), err) }()
+
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	all, err = AllFromRows(withoutOnComplete(withQueryArgs(ctx, args)), m, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ra, ok := rows.(RowsAffecter)
+	if !ok {
+		return all, int64(len(all)), nil
+	}
+
+	// Some drivers only report the count after the rows are closed
+	if err := rows.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	affected, err = ra.RowsAffected()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return all, affected, nil
+}
+
+// AllWithMatched works like [All] but also returns the columns that were matched
+// to fields by a struct mapper. This is useful to find out why a field was not set.
+// For mappers that are not struct mappers, the matched columns are nil
+func AllWithMatched[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) ([]T, []string, error) {
+	var matched []string
+	ctx = context.WithValue(ctx, ctxKeyMatchedColumns, &matched)
+
+	all, err := All(ctx, exec, m, query, args...)
+	return all, matched, err
+}
+
 // Cursor runs a query and returns a cursor that works similar to *sql.Rows
 func Cursor[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) (ICursor[T], error) {
 	rows, err := exec.QueryContext(ctx, query, args...)
@@ -86,7 +465,32 @@ func Cursor[T any](ctx context.Context, exec Queryer, m Mapper[T], query string,
 		return nil, err
 	}
 
-	return CursorFromRows(ctx, m, rows)
+	return CursorFromRows(withQueryArgs(ctx, args), m, rows)
+}
+
+// CursorBuffered runs a query and scans all the rows into memory.
+// The returned cursor can be iterated over multiple times using Reset.
+// Use [Cursor] to scan each row on demand instead
+func CursorBuffered[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) (BufferedCursor[T], error) {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		onComplete(ctx)(0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return CursorBufferedFromRows(withQueryArgs(ctx, args), m, rows)
+}
+
+// CursorBufferedFromRows scans all the rows from [Rows] into memory and returns
+// a cursor that can be iterated over multiple times
+func CursorBufferedFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) (BufferedCursor[T], error) {
+	all, err := AllFromRows(ctx, m, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bufferedCursor[T]{rows: all, pos: -1}, nil
 }
 
 // Each returns a function that can be used to iterate over the rows of a query
@@ -99,36 +503,167 @@ func Cursor[T any](ctx context.Context, exec Queryer, m Mapper[T], query string,
 //	    // do something with val
 //	}
 func Each[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) func(func(T, error) bool) {
+	complete := onComplete(ctx)
+
 	rows, err := exec.QueryContext(ctx, query, args...)
 	if err != nil {
-		return func(yield func(T, error) bool) { yield(*new(T), err) }
+		return func(yield func(T, error) bool) {
+			complete(0, err)
+			yield(*new(T), err)
+		}
 	}
 
-	allowUnknown, _ := ctx.Value(CtxKeyAllowUnknownColumns).(bool)
-	wrapped, err := wrapRows(rows, allowUnknown)
+	wrapped, err := wrapRows(ctx, rows)
 	if err != nil {
 		rows.Close()
-		return func(yield func(T, error) bool) { yield(*new(T), err) }
+		return func(yield func(T, error) bool) {
+			complete(0, err)
+			yield(*new(T), err)
+		}
 	}
 
-	before, after := m(ctx, wrapped.columnsCopy())
+	before, after := m(withQueryArgs(ctx, args), wrapped.columnsCopy())
 
 	return func(yield func(T, error) bool) {
+		var n int
+		var firstErr error
+		defer func() { complete(n, firstErr) }()
 		defer rows.Close()
 
 		for rows.Next() {
+			if err := ctx.Err(); err != nil {
+				firstErr = err
+				yield(*new(T), err)
+				return
+			}
+
 			val, err := scanOneRow(wrapped, before, after)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if err == nil {
+				n++
+			}
+
 			if !yield(val, err) {
 				return
 			}
 		}
+
+		if firstErr == nil {
+			firstErr = rows.Err()
+		}
+	}
+}
+
+// EachIndexed works like [Each] but also passes the zero-based index of the row.
+// If scanning a row fails, the index of the row is passed with the error.
+// Since range-over-func only supports up to two values, the function is called directly
+//
+//	scan.EachIndexed(ctx, exec, m, query, args...)(func(i int, val T, err error) bool {
+//	    if err != nil {
+//	        return false
+//	    }
+//	    // do something with i and val
+//	    return true
+//	})
+func EachIndexed[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) func(func(int, T, error) bool) {
+	each := Each(ctx, exec, m, query, args...)
+
+	return func(yield func(int, T, error) bool) {
+		var i int
+		each(func(val T, err error) bool {
+			ok := yield(i, val, err)
+			i++
+			return ok
+		})
+	}
+}
+
+// Batches returns a function that can be used to iterate over the rows of a query
+// in batches of up to size rows. Like [Each], it works with range-over-func.
+//
+// The same slice is reused for every batch, so it is overwritten on the next
+// iteration. Copy the batch if it needs to be kept.
+//
+//	for batch, err := range scan.Batches(ctx, exec, m, 100, query, args...) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // do something with batch
+//	}
+func Batches[T any](ctx context.Context, exec Queryer, m Mapper[T], size int, query string, args ...any) func(func([]T, error) bool) {
+	complete := onComplete(ctx)
+	failed := func(err error) func(func([]T, error) bool) {
+		return func(yield func([]T, error) bool) {
+			complete(0, err)
+			yield(nil, err)
+		}
+	}
+
+	if size < 1 {
+		return failed(fmt.Errorf("batch size must be at least 1, got %d", size))
+	}
+
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return failed(err)
+	}
+
+	wrapped, err := wrapRows(ctx, rows)
+	if err != nil {
+		rows.Close()
+		return failed(err)
+	}
+
+	before, after := m(withQueryArgs(ctx, args), wrapped.columnsCopy())
+
+	return func(yield func([]T, error) bool) {
+		var n int
+		var err error
+		defer func() { complete(n, err) }()
+		defer rows.Close()
+
+		batch := make([]T, 0, size)
+		for rows.Next() {
+			if err = ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			var val T
+			val, err = scanOneRow(wrapped, before, after)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			n++
+			batch = append(batch, val)
+			if len(batch) < size {
+				continue
+			}
+
+			if !yield(batch, nil) {
+				return
+			}
+			batch = batch[:0]
+		}
+
+		if err = rows.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		if len(batch) > 0 {
+			yield(batch, nil)
+		}
 	}
 }
 
 // CursorFromRows returns a cursor from [Rows] that works similar to *sql.Rows
 func CursorFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) (ICursor[T], error) {
-	allowUnknown, _ := ctx.Value(CtxKeyAllowUnknownColumns).(bool)
-	v, err := wrapRows(rows, allowUnknown)
+	v, err := wrapRows(ctx, rows)
 	if err != nil {
 		return nil, err
 	}
@@ -142,7 +677,130 @@ func CursorFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) (ICursor
 	}, nil
 }
 
+// CursorPrefetch works like [Cursor] but scans up to bufferSize rows ahead in a goroutine
+// while the current row is being processed. This overlaps reading from a slow
+// network with the work done on each row.
+//
+// Errors from scanning a row are returned by Get for that row and errors from
+// the rows are returned by Err once Next returns false. Close must be called,
+// even if the rows are not read to the end, to stop the goroutine and close the rows.
+// The cursor should only be used from a single goroutine
+func CursorPrefetch[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, bufferSize int, args ...any) (ICursor[T], error) {
+	if bufferSize < 1 {
+		return nil, fmt.Errorf("buffer size must be at least 1, got %d", bufferSize)
+	}
+
+	// Cancelling the query stops a read that is in progress when the cursor is closed
+	ctx, cancel := context.WithCancel(ctx)
+
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	c, err := cursorPrefetchFromRows(withQueryArgs(ctx, args), m, rows, bufferSize, cancel)
+	if err != nil {
+		rows.Close()
+		cancel()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// CursorPrefetchFromRows works like [CursorPrefetch] but reads the rows from the given [Rows].
+//
+// Close stops reading and closes the rows, but since the query was run elsewhere, it cannot
+// interrupt a read that is in progress and waits for it to return. If reading the rows
+// can block, cancel the context the query was run with to release them
+func CursorPrefetchFromRows[T any](ctx context.Context, m Mapper[T], rows Rows, bufferSize int) (ICursor[T], error) {
+	if bufferSize < 1 {
+		return nil, fmt.Errorf("buffer size must be at least 1, got %d", bufferSize)
+	}
+
+	// Cancelled on Close to stop the mapper and the goroutine between rows
+	ctx, cancel := context.WithCancel(ctx)
+
+	c, err := cursorPrefetchFromRows(ctx, m, rows, bufferSize, cancel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func cursorPrefetchFromRows[T any](ctx context.Context, m Mapper[T], rows Rows, bufferSize int, cancel func()) (ICursor[T], error) {
+	v, err := wrapRows(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	before, after := m(ctx, v.columnsCopy())
+
+	results := make(chan prefetched[T], bufferSize)
+	c := &prefetchCursor[T]{
+		results: results,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		cancel:  cancel,
+	}
+
+	go c.prefetch(v, before, after, results)
+
+	return c, nil
+}
+
+// WithOnComplete returns a context that makes the query functions call fn once
+// when they are done with the number of rows scanned and the first error, if any.
+// This is useful for metrics and logging without wrapping every call.
+//
+// Like the CtxKey settings, it is passed with the context since the query
+// functions do not take options.
+//
+// It is called by [One], [OneOrZero], [All], [AllWhile], [AllTolerant], [AllMap], [AllSet],
+// [AllWithMatched], [AllWithRowsAffected], [AllMulti], [ResultSets], [Collect2], [Collect3],
+// [GroupByKeys], [CursorBuffered], [Each], [EachIndexed] and [Batches], and by the
+// FromRows variants of these functions. It is not called by [ScanRow], [Cursor],
+// [CursorPrefetch], [EncodeCSV] or their variants.
+//
+// For [AllWithRowsAffected], fn is called after the rows are closed and the count is read.
+// For [Each] and [Batches], fn is called when the iteration ends, including when
+// the loop is exited early
+func WithOnComplete(ctx context.Context, fn func(n int, err error)) context.Context {
+	return context.WithValue(ctx, ctxKeyOnComplete, fn)
+}
+
+// onComplete returns the function set with [WithOnComplete] or a no-op
+func onComplete(ctx context.Context) func(int, error) {
+	fn, _ := ctx.Value(ctxKeyOnComplete).(func(int, error))
+	if fn == nil {
+		return func(int, error) {}
+	}
+
+	return fn
+}
+
+// withoutOnComplete stops the function set with [WithOnComplete] from being called
+// by the functions used with ctx, for callers that call it once themselves
+func withoutOnComplete(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyOnComplete, nil)
+}
+
+// withQueryArgs makes the query args available to the mapper
+func withQueryArgs(ctx context.Context, args []any) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, ctxKeyQueryArgs, args)
+}
+
 func scanOneRow[T any](v *Row, before func(*Row) (any, error), after func(any) (T, error)) (T, error) {
+	// The values of the previous row are not valid for this row
+	v.scanned = nil
+
 	val, err := before(v)
 	if err != nil {
 		var t T
@@ -1,9 +1,13 @@
 package scan
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -219,6 +223,120 @@ func TestColumnValue(t *testing.T) {
 		mapper:      ColumnMapper[int]("unknown_column"),
 		expectedErr: createError(nil, "unknown_column"),
 	})
+
+	testQuery(t, "by index", queryCase[string]{
+		ctx:       context.WithValue(context.Background(), CtxKeyAllowUnknownColumns, true),
+		columns:   strstr{{"id", "int64"}, {"name", "string"}},
+		rows:      rows{[]any{1, "foo"}, []any{2, "bar"}},
+		query:     []string{"id", "name"},
+		mapper:    ColumnMapperIndex[string](1),
+		expectOne: "foo",
+		expectAll: []string{"foo", "bar"},
+	})
+
+	testQuery(t, "index out of range", queryCase[int]{
+		columns:     strstr{{"id", "int64"}},
+		rows:        singleRows(1, 2, 3),
+		query:       []string{"id"},
+		mapper:      ColumnMapperIndex[int](1),
+		expectedErr: createError(nil, "column index out of range"),
+	})
+
+	type status string
+	toStatus := func(code int) (status, error) {
+		switch code {
+		case 1:
+			return "active", nil
+		case 2:
+			return "inactive", nil
+		}
+
+		return "", fmt.Errorf("unknown status %d", code)
+	}
+
+	testQuery(t, "with conversion", queryCase[status]{
+		columns:   strstr{{"status", "int64"}},
+		rows:      singleRows(1, 2, 1),
+		query:     []string{"status"},
+		mapper:    ColumnMapperFunc("status", toStatus),
+		expectOne: "active",
+		expectAll: []status{"active", "inactive", "active"},
+	})
+
+	testQuery(t, "with failed conversion", queryCase[status]{
+		columns:     strstr{{"status", "int64"}},
+		rows:        singleRows(3),
+		query:       []string{"status"},
+		mapper:      ColumnMapperFunc("status", toStatus),
+		expectedErr: createError(nil, "convert", "status"),
+	})
+}
+
+func TestPair(t *testing.T) {
+	testQuery(t, "pairs", queryCase[Pair[string, int]]{
+		columns:   strstr{{"key", "string"}, {"value", "int64"}},
+		rows:      rows{[]any{"a", 1}, []any{"b", 2}},
+		query:     []string{"key", "value"},
+		mapper:    PairMapper[string, int]("key", "value"),
+		expectOne: Pair[string, int]{Key: "a", Value: 1},
+		expectAll: []Pair[string, int]{{Key: "a", Value: 1}, {Key: "b", Value: 2}},
+	})
+
+	pairs := []Pair[string, int]{{Key: "a", Value: 1}, {Key: "b", Value: 2}, {Key: "a", Value: 3}}
+	if diff := cmp.Diff(map[string]int{"a": 3, "b": 2}, ToMap(pairs)); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	ex, clean := createDB(t, strstr{{"key", "string"}, {"value", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"key", "value"}, []any{"a", 1}, []any{"b", 2}, []any{"a", 3})
+	query := createQuery(t, []string{"key", "value"})
+
+	settings, err := AllMap[string, int](context.Background(), stdQ{ex}, "key", "value", query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff(map[string]int{"a": 3, "b": 2}, settings); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestTuple(t *testing.T) {
+	testQuery(t, "positional", queryCase[Tuple2[string, int]]{
+		columns:   strstr{{"name", "string"}, {"count", "int64"}},
+		rows:      rows{[]any{"a", 1}, []any{"b", 2}},
+		query:     []string{"name", "count"},
+		mapper:    TupleMapper2[string, int](),
+		expectOne: Tuple2[string, int]{V1: "a", V2: 1},
+		expectAll: []Tuple2[string, int]{{V1: "a", V2: 1}, {V1: "b", V2: 2}},
+	})
+
+	testQuery(t, "by name", queryCase[Tuple3[int, string, bool]]{
+		columns:   strstr{{"name", "string"}, {"id", "int64"}, {"ignored", "int64"}, {"active", "bool"}},
+		rows:      rows{[]any{"a", 1, 0, true}, []any{"b", 2, 0, false}},
+		query:     []string{"name", "id", "active"},
+		mapper:    TupleMapper3[int, string, bool]("id", "name", "active"),
+		expectOne: Tuple3[int, string, bool]{V1: 1, V2: "a", V3: true},
+		expectAll: []Tuple3[int, string, bool]{{V1: 1, V2: "a", V3: true}, {V1: 2, V2: "b", V3: false}},
+	})
+
+	testQuery(t, "wrong column count", queryCase[Tuple2[string, int]]{
+		columns:     strstr{{"name", "string"}, {"count", "int64"}, {"id", "int64"}},
+		rows:        rows{[]any{"a", 1, 1}},
+		query:       []string{"name", "count", "id"},
+		mapper:      TupleMapper2[string, int](),
+		expectedErr: createError(nil, "wrong column count", "2", "3"),
+	})
+
+	testQuery(t, "wrong name count", queryCase[Tuple3[int, string, bool]]{
+		columns:     strstr{{"id", "int64"}, {"name", "string"}},
+		rows:        rows{[]any{1, "a"}},
+		query:       []string{"id", "name"},
+		mapper:      TupleMapper3[int, string, bool]("id", "name"),
+		expectedErr: createError(nil, "wrong tuple column count", "3", "2"),
+	})
 }
 
 func TestMap(t *testing.T) {
@@ -233,6 +351,36 @@ func TestMap(t *testing.T) {
 		expectOne: user1,
 		expectAll: []map[string]any{user1, user2},
 	})
+
+	testQuery(t, "json", queryCase[json.RawMessage]{
+		columns:   strstr{{"id", "int64"}, {"name", "nullstring"}},
+		rows:      rows{[]any{1, nil}, []any{2, "bar"}},
+		query:     []string{"id", "name"},
+		mapper:    JSONRowMapper(),
+		expectOne: json.RawMessage(`{"id":1,"name":null}`),
+		expectAll: []json.RawMessage{
+			json.RawMessage(`{"id":1,"name":null}`),
+			json.RawMessage(`{"id":2,"name":"bar"}`),
+		},
+	})
+
+	testQuery(t, "omit null", queryCase[map[string]any]{
+		columns:   strstr{{"id", "int64"}, {"name", "nullstring"}},
+		rows:      rows{[]any{1, nil}, []any{2, "bar"}},
+		query:     []string{"id", "name"},
+		mapper:    MapMapperOmitNull[any],
+		expectOne: map[string]any{"id": int64(1)},
+		expectAll: []map[string]any{{"id": int64(1)}, user2},
+	})
+
+	testQuery(t, "omit null typed", queryCase[map[string]string]{
+		columns:   strstr{{"a", "nullstring"}, {"b", "nullstring"}},
+		rows:      rows{[]any{"x", nil}, []any{nil, "y"}},
+		query:     []string{"a", "b"},
+		mapper:    MapMapperOmitNull[string],
+		expectOne: map[string]string{"a": "x"},
+		expectAll: []map[string]string{{"a": "x"}, {"b": "y"}},
+	})
 }
 
 func TestStruct(t *testing.T) {
@@ -335,4 +483,2106 @@ func TestAllowUnknownColumns(t *testing.T) {
 		expectOne: testStruct{ID: 1, Int: 1},
 		expectAll: []testStruct{{ID: 1, Int: 1}, {ID: 2, Int: 2}},
 	})
+
+	// succeeds when the mapper is created with WithAllowUnknownColumns
+	testQuery(t, "unknowncolumnsallowedoption", queryCase[testStruct]{
+		columns:   strstr{{"id", "int64"}, {"ignored_int", "int64"}, {"int", "int64"}},
+		rows:      rows{{1, 10, 1}, {2, 20, 2}},
+		query:     []string{"id", "ignored_int", "int"},
+		mapper:    StructMapper[testStruct](WithAllowUnknownColumns(true)),
+		expectOne: testStruct{ID: 1, Int: 1},
+		expectAll: []testStruct{{ID: 1, Int: 1}, {ID: 2, Int: 2}},
+	})
+
+	// duplicate columns are also discarded
+	for _, tc := range []struct {
+		name   string
+		ctx    context.Context
+		mapper Mapper[testStruct]
+	}{
+		{
+			name:   "duplicatecolumnsallowed",
+			ctx:    context.WithValue(context.Background(), CtxKeyAllowUnknownColumns, true),
+			mapper: StructMapper[testStruct](),
+		},
+		{
+			name:   "duplicatecolumnsallowedoption",
+			mapper: StructMapper[testStruct](WithAllowUnknownColumns(true)),
+		},
+	} {
+		testQuery(t, tc.name, queryCase[testStruct]{
+			ctx:       tc.ctx,
+			columns:   strstr{{"id", "int64"}, {"ignored_int", "int64"}, {"int", "int64"}},
+			rows:      rows{{1, 10, 1}, {2, 20, 2}},
+			query:     []string{"id", "ignored_int", "int", "ignored_int", "id"},
+			mapper:    tc.mapper,
+			expectOne: testStruct{ID: 1, Int: 1},
+			expectAll: []testStruct{{ID: 1, Int: 1}, {ID: 2, Int: 2}},
+		})
+	}
+
+	// succeeds and reports the discarded columns once per query
+	for name, allow := range map[string]bool{"warnunknowncolumns": false, "warnunknowncolumnsallowed": true} {
+		var warned [][]string
+		testQuery(t, name, queryCase[testStruct]{
+			columns: strstr{{"id", "int64"}, {"ignored_int", "int64"}, {"int", "int64"}, {"ignored_too", "int64"}},
+			rows:    rows{{1, 10, 1, 100}, {2, 20, 2, 200}},
+			query:   []string{"id", "ignored_int", "int", "ignored_too"},
+			mapper: StructMapper[testStruct](
+				WithAllowUnknownColumns(allow),
+				WithWarnUnknownColumns(func(cols []string) { warned = append(warned, cols) }),
+			),
+			expectOne: testStruct{ID: 1, Int: 1},
+			expectAll: []testStruct{{ID: 1, Int: 1}, {ID: 2, Int: 2}},
+		})
+
+		// once for each query: One, All, Each and Cursor
+		ignored := []string{"ignored_int", "ignored_too"}
+		expected := [][]string{ignored, ignored, ignored, ignored}
+		if diff := cmp.Diff(expected, warned); diff != "" {
+			t.Fatalf("%s diff: %s", name, diff)
+		}
+	}
+}
+
+func TestPointerToPointer(t *testing.T) {
+	ptrPtr := func(v int) **int {
+		p := &v
+		return &p
+	}
+
+	expected := []PtrPtrUser{
+		{
+			ID:    ptrPtr(1),
+			Name:  toPtr(toPtr("The Name")),
+			Owner: toPtr(&Owner{ID: 3, Name: "The Owner"}),
+		},
+		{
+			ID:    ptrPtr(2),
+			Owner: toPtr(&Owner{ID: 4, Name: "Another Owner"}),
+		},
+	}
+
+	for name, mapper := range map[string]Mapper[PtrPtrUser]{
+		"regular":   StructMapper[PtrPtrUser](),
+		"converter": StructMapper[PtrPtrUser](WithTypeConverter(typeConverter{})),
+	} {
+		testQuery(t, name, queryCase[PtrPtrUser]{
+			columns:   strstr{{"id", "int64"}, {"name", "nullstring"}, {"owner.id", "int64"}, {"owner.name", "string"}},
+			rows:      rows{{1, "The Name", 3, "The Owner"}, {2, nil, 4, "Another Owner"}},
+			query:     []string{"id", "name", "owner.id", "owner.name"},
+			mapper:    mapper,
+			expectOne: expected[0],
+			expectAll: expected,
+		})
+	}
+
+	testQuery(t, "trimmed", queryCase[PtrPtrUser]{
+		columns:   strstr{{"id", "int64"}, {"name", "string"}},
+		rows:      rows{{1, "The Name  "}},
+		query:     []string{"id", "name"},
+		mapper:    StructMapper[PtrPtrUser](WithTrimStringColumns("name")),
+		expectOne: PtrPtrUser{ID: ptrPtr(1), Name: toPtr(toPtr("The Name"))},
+		expectAll: []PtrPtrUser{{ID: ptrPtr(1), Name: toPtr(toPtr("The Name"))}},
+	})
+}
+
+func TestCompiledMapper(t *testing.T) {
+	user1 := User{ID: 1, Name: "foo"}
+	user2 := User{ID: 2, Name: "bar"}
+
+	compiled, err := Compile(StructMapper[User](), []string{"id", "name"})
+	if err != nil {
+		t.Fatalf("could not compile mapper: %v", err)
+	}
+
+	testQuery(t, "matching", queryCase[User]{
+		columns:   strstr{{"id", "int64"}, {"name", "string"}},
+		rows:      rows{[]any{1, "foo"}, []any{2, "bar"}},
+		query:     []string{"id", "name"},
+		mapper:    compiled.Mapper(),
+		expectOne: user1,
+		expectAll: []User{user1, user2},
+	})
+
+	testQuery(t, "mismatched", queryCase[User]{
+		columns:     strstr{{"id", "int64"}, {"name", "string"}},
+		rows:        rows{[]any{1, "foo"}, []any{2, "bar"}},
+		query:       []string{"name", "id"},
+		mapper:      compiled.Mapper(),
+		expectedErr: createError(nil, "compiled columns mismatch"),
+	})
+
+	if _, err := Compile[User](nil, nil); err == nil {
+		t.Fatal("expected error compiling nil mapper")
+	}
+
+	_, err = Compile(StructMapper[User](), []string{"id", "name", "other"})
+	if diff := diffErr(createError(nil, "no destination", "other"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	_, err = Compile(StructMapper[User](), []string{"not_id"})
+	if diff := diffErr(createError(nil, "no destination", "not_id"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	ctx := context.WithValue(context.Background(), CtxKeyAllowUnknownColumns, true)
+	if _, err := CompileContext(ctx, StructMapper[User](), []string{"id", "name", "other"}); err != nil {
+		t.Fatalf("could not compile mapper allowing unknown columns: %v", err)
+	}
+}
+
+func TestAllWithMatched(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}, {"other", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name", "other"}, []any{1, "foo", "x"})
+
+	ctx := context.WithValue(context.Background(), CtxKeyAllowUnknownColumns, true)
+	query := createQuery(t, []string{"id", "other", "name"})
+
+	all, matched, err := AllWithMatched(ctx, stdQ{ex}, StructMapper[User](), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]User{{ID: 1, Name: "foo"}}, all); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"id", "name"}, matched); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	type post struct {
+		ID    int64
+		Title string
+	}
+
+	type author struct {
+		ID    int64
+		Name  string
+		Posts []post
+	}
+
+	ex, clean := createDB(t, strstr{
+		{"id", "int64"},
+		{"name", "string"},
+		{"post_id", "int64"},
+		{"post_title", "string"},
+	})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name", "post_id", "post_title"},
+		[]any{1, "foo", 10, "first"},
+		[]any{2, "bar", 20, "second"},
+		[]any{1, "foo", 11, "third"},
+	)
+
+	query := createQuery(t, []string{"id", "name", "post_id", "post_title"})
+	joined, err := All(context.Background(), stdQ{ex}, JoinMapper(
+		StructMapper[author](),
+		StructMapper[post](WithStructTagPrefix("post_")),
+	), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	authors := GroupBy(joined,
+		func(a author) int64 { return a.ID },
+		func(a *author, p post) { a.Posts = append(a.Posts, p) },
+	)
+
+	expected := []author{
+		{ID: 1, Name: "foo", Posts: []post{{ID: 10, Title: "first"}, {ID: 11, Title: "third"}}},
+		{ID: 2, Name: "bar", Posts: []post{{ID: 20, Title: "second"}}},
+	}
+
+	if diff := cmp.Diff(expected, authors); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestGroupByKeys(t *testing.T) {
+	type post struct {
+		ID    int64
+		Title string
+	}
+
+	type member struct {
+		OrgID int64
+		ID    int64
+		Name  string
+		Posts []post
+	}
+
+	cols := []string{"org_id", "id", "name", "post_id", "post_title"}
+	ex, clean := createDB(t, strstr{
+		{"org_id", "int64"},
+		{"id", "int64"},
+		{"name", "string"},
+		{"post_id", "int64"},
+		{"post_title", "string"},
+	})
+	defer clean()
+
+	insert(t, ex, cols,
+		[]any{1, 1, "foo", 10, "first"},
+		[]any{2, 1, "bar", 20, "second"},
+		[]any{1, 2, "baz", 30, "third"},
+		[]any{1, 1, "foo", 11, "fourth"},
+		[]any{2, 1, "bar", 21, "fifth"},
+	)
+
+	query := createQuery(t, cols)
+	add := func(m *member, p post) { m.Posts = append(m.Posts, p) }
+
+	members, err := GroupByKeys(context.Background(), stdQ{ex},
+		StructMapper[member](),
+		StructMapper[post](WithStructTagPrefix("post_")),
+		[]string{"org_id", "id"}, add, query,
+	)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	expected := []member{
+		{OrgID: 1, ID: 1, Name: "foo", Posts: []post{{ID: 10, Title: "first"}, {ID: 11, Title: "fourth"}}},
+		{OrgID: 2, ID: 1, Name: "bar", Posts: []post{{ID: 20, Title: "second"}, {ID: 21, Title: "fifth"}}},
+		{OrgID: 1, ID: 2, Name: "baz", Posts: []post{{ID: 30, Title: "third"}}},
+	}
+
+	if diff := cmp.Diff(expected, members); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	_, err = GroupByKeys(context.Background(), stdQ{ex},
+		StructMapper[member](),
+		StructMapper[post](WithStructTagPrefix("post_")),
+		[]string{"org_id", "team_id"}, add, query,
+	)
+	if diff := diffErr(createError(nil, "unknown key column", "team_id"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}, {"post_count", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name", "post_count"}, []any{1, "foo", 3}, []any{2, "bar", 0})
+	query := createQuery(t, []string{"id", "name", "post_count"})
+
+	users, counts, err := Collect2(context.Background(), stdQ{ex},
+		StructMapper[User](), ColumnMapper[int]("post_count"), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]User{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}}, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]int{3, 0}, counts); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	ids, names, counts, err := Collect3(context.Background(), stdQ{ex},
+		ColumnMapper[int]("id"), ColumnMapper[string]("name"), ColumnMapper[int]("post_count"), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]int{1, 2}, ids); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"foo", "bar"}, names); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]int{3, 0}, counts); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestJoinMapperSharedColumns(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}, {"post_count", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name", "post_count"}, []any{1, "foo", 3}, []any{2, "bar", 0})
+	query := createQuery(t, []string{"id", "name", "post_count"})
+
+	users, names, ids, err := Collect3(context.Background(), stdQ{ex},
+		StructMapper[User](WithAllowUnknownColumns(true)), ColumnMapper[string]("name"), ColumnMapper[int]("id"), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]User{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}}, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"foo", "bar"}, names); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]int{1, 2}, ids); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	// The discarded post_count column of the struct mapper
+	// does not replace the typed destination
+	counts, users, err := Collect2(context.Background(), stdQ{ex},
+		ColumnMapper[int]("post_count"), StructMapper[User](WithAllowUnknownColumns(true)), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]int{3, 0}, counts); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]User{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}}, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	upper := func(ctx context.Context, c cols) (BeforeFunc, func(any) (string, error)) {
+		return func(v *Row) (any, error) {
+				var name string
+				v.ScheduleScanConvert("name", reflect.ValueOf(&name), func(val reflect.Value) error {
+					val.Elem().SetString(strings.ToUpper(val.Elem().String()))
+					return nil
+				})
+				return &name, nil
+			}, func(link any) (string, error) {
+				return *link.(*string), nil
+			}
+	}
+
+	uppers, names, err := Collect2(context.Background(), stdQ{ex},
+		upper, ColumnMapper[string]("name"), createQuery(t, []string{"name"}))
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"FOO", "BAR"}, uppers); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"foo", "bar"}, names); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	_, _, err = Collect2(context.Background(), stdQ{ex},
+		ColumnMapper[int]("id"), ColumnMapper[string]("id"), createQuery(t, []string{"id"}))
+	if diff := diffErr(createError(nil, "conflicting destinations", "id"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, singleRows(1, 2, 3, 4, 5, 6, 7)...)
+	query := createQuery(t, []string{"id"})
+
+	var batches [][]int
+	Batches(context.Background(), stdQ{ex}, SingleColumnMapper[int], 3, query)(func(batch []int, err error) bool {
+		if err != nil {
+			t.Fatalf("error getting batch: %v", err)
+		}
+
+		batches = append(batches, append([]int(nil), batch...))
+		return true
+	})
+
+	if diff := cmp.Diff([][]int{{1, 2, 3}, {4, 5, 6}, {7}}, batches); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	var gotErr error
+	Batches(context.Background(), stdQ{ex}, SingleColumnMapper[int], 0, query)(func(batch []int, err error) bool {
+		gotErr = err
+		return false
+	})
+
+	if gotErr == nil {
+		t.Fatal("expected error for invalid batch size")
+	}
+}
+
+type affectedRows struct {
+	Rows
+	affected int64
+	err      error
+}
+
+func (a affectedRows) RowsAffected() (int64, error) {
+	return a.affected, a.err
+}
+
+type affectedQ struct {
+	stdQ
+	affected int64
+	err      error
+}
+
+func (a affectedQ) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	rows, err := a.stdQ.QueryContext(ctx, query, args...)
+	return affectedRows{Rows: rows, affected: a.affected, err: a.err}, err
+}
+
+func TestAllWithRowsAffected(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, singleRows(1, 2, 3)...)
+	query := createQuery(t, []string{"id"})
+
+	all, affected, err := AllWithRowsAffected(context.Background(), stdQ{ex}, SingleColumnMapper[int], query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+	if diff := cmp.Diff([]int{1, 2, 3}, all); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+	if affected != 3 {
+		t.Fatalf("expected fallback count of 3, got %d", affected)
+	}
+
+	_, affected, err = AllWithRowsAffected(context.Background(), affectedQ{stdQ: stdQ{ex}, affected: 10}, SingleColumnMapper[int], query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+	if affected != 10 {
+		t.Fatalf("expected reported count of 10, got %d", affected)
+	}
+
+	var calls []error
+	ctx := WithOnComplete(context.Background(), func(n int, err error) {
+		calls = append(calls, err)
+	})
+
+	affectedErr := errors.New("no count")
+	_, _, err = AllWithRowsAffected(ctx, affectedQ{stdQ: stdQ{ex}, err: affectedErr}, SingleColumnMapper[int], query)
+	if !errors.Is(err, affectedErr) {
+		t.Fatalf("expected count error, got %v", err)
+	}
+	if len(calls) != 1 || !errors.Is(calls[0], affectedErr) {
+		t.Fatalf("expected one call with the count error, got %v", calls)
+	}
+
+	calls = nil
+	_, _, err = AllWithRowsAffected(ctx, stdQ{ex}, SingleColumnMapper[int], "SELECT nope FROM")
+	if err == nil {
+		t.Fatal("expected query error")
+	}
+	if len(calls) != 1 || calls[0] != err {
+		t.Fatalf("expected one call with the query error, got %v", calls)
+	}
+}
+
+func TestCursorBuffered(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, singleRows(1, 2, 3)...)
+	query := createQuery(t, []string{"id"})
+
+	c, err := CursorBuffered(context.Background(), stdQ{ex}, SingleColumnMapper[int], query)
+	if err != nil {
+		t.Fatalf("error getting cursor: %v", err)
+	}
+	defer c.Close()
+
+	if c.Len() != 3 {
+		t.Fatalf("expected 3 rows, got %d", c.Len())
+	}
+
+	if _, err := c.Get(); err == nil {
+		t.Fatal("expected error calling Get before Next")
+	}
+
+	for pass := 0; pass < 2; pass++ {
+		var got []int
+		for c.Next() {
+			v, err := c.Get()
+			if err != nil {
+				t.Fatalf("error getting row: %v", err)
+			}
+			got = append(got, v)
+		}
+
+		if diff := cmp.Diff([]int{1, 2, 3}, got); diff != "" {
+			t.Fatalf("pass %d diff: %s", pass, diff)
+		}
+
+		c.Reset()
+	}
+}
+
+type closeTrackingRows struct {
+	Rows
+	closed *int
+}
+
+func (c closeTrackingRows) Close() error {
+	*c.closed++
+	return c.Rows.Close()
+}
+
+func TestCursorPrefetch(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "nullint64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, []any{1}, []any{nil}, []any{3}, []any{4}, []any{5})
+	query := createQuery(t, []string{"id"})
+
+	c, err := CursorPrefetch(context.Background(), stdQ{ex}, SingleColumnMapper[int], query, 2)
+	if err != nil {
+		t.Fatalf("error getting cursor: %v", err)
+	}
+
+	var got []int
+	var failed int
+	for c.Next() {
+		v, err := c.Get()
+		if err != nil {
+			failed++
+			continue
+		}
+		got = append(got, v)
+	}
+
+	if err := c.Err(); err != nil {
+		t.Fatalf("error from rows: %v", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("error closing cursor: %v", err)
+	}
+
+	if diff := cmp.Diff([]int{1, 3, 4, 5}, got); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if failed != 1 {
+		t.Fatalf("expected 1 failed row, got %d", failed)
+	}
+
+	// Closing early must close the rows even though they were not read to the end
+	rows, err := ex.Query(query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	var closed int
+	c, err = CursorPrefetchFromRows(context.Background(), SingleColumnMapper[int], closeTrackingRows{Rows: rows, closed: &closed}, 1)
+	if err != nil {
+		t.Fatalf("error getting cursor: %v", err)
+	}
+
+	if !c.Next() {
+		t.Fatal("expected a row")
+	}
+
+	if v, err := c.Get(); err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d, %v", v, err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("error closing cursor: %v", err)
+	}
+
+	if closed != 1 {
+		t.Fatalf("expected the rows to be closed once, got %d", closed)
+	}
+
+	if c.Next() {
+		t.Fatal("expected no rows after closing")
+	}
+
+	if _, err := CursorPrefetch(context.Background(), stdQ{ex}, SingleColumnMapper[int], query, 0); err == nil {
+		t.Fatal("expected an error for a buffer size of 0")
+	}
+}
+
+// slowRows returns rows without end, waiting before each of them
+type slowRows struct {
+	delay  time.Duration
+	closed chan struct{}
+}
+
+func (s *slowRows) Scan(dest ...any) error {
+	*dest[0].(*int) = 1
+	return nil
+}
+
+func (s *slowRows) Columns() ([]string, error) { return []string{"id"}, nil }
+
+func (s *slowRows) Next() bool {
+	time.Sleep(s.delay)
+	return true
+}
+
+func (s *slowRows) Close() error {
+	close(s.closed)
+	return nil
+}
+
+func (s *slowRows) Err() error { return nil }
+
+func TestCursorPrefetchCloseSlowRows(t *testing.T) {
+	rows := &slowRows{delay: 20 * time.Millisecond, closed: make(chan struct{})}
+
+	c, err := CursorPrefetchFromRows(context.Background(), SingleColumnMapper[int], rows, 4)
+	if err != nil {
+		t.Fatalf("error getting cursor: %v", err)
+	}
+
+	if !c.Next() {
+		t.Fatal("expected a row")
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- c.Close() }()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("error closing cursor: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("closing the cursor did not return")
+	}
+
+	select {
+	case <-rows.closed:
+	default:
+		t.Fatal("expected the rows to be closed")
+	}
+}
+
+func TestTimeDefinedType(t *testing.T) {
+	birthday := randate()
+	anniversary := randate()
+
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"birthday", "datetime"}, {"anniversary", "datetime"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "birthday", "anniversary"}, []any{1, birthday, anniversary})
+	query := createQuery(t, []string{"id", "birthday", "anniversary"})
+
+	user, err := One(context.Background(), stdQ{ex}, StructMapper[DateUser](), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if got := time.Time(user.Birthday); !got.Equal(birthday) {
+		t.Fatalf("expected birthday %v, got %v", birthday, got)
+	}
+
+	if user.Anniversary == nil {
+		t.Fatal("expected anniversary to be set")
+	}
+
+	if got := time.Time(*user.Anniversary); !got.Equal(anniversary) {
+		t.Fatalf("expected anniversary %v, got %v", anniversary, got)
+	}
+}
+
+type upperQ struct {
+	stdQ
+}
+
+func (u upperQ) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	rows, err := u.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return AdaptRows(rows, func() ([]string, error) {
+		cols, err := rows.Columns()
+		for i := range cols {
+			cols[i] = strings.ToUpper(cols[i])
+		}
+		return cols, err
+	}, nil), nil
+}
+
+func TestAdaptRows(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"})
+	query := createQuery(t, []string{"id", "name"})
+
+	m, err := One(context.Background(), upperQ{stdQ{ex}}, MapMapper[any], query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff(map[string]any{"ID": int64(1), "NAME": "foo"}, m); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestDiagnoseScanErrors(t *testing.T) {
+	testQuery(t, "diagnosed", queryCase[User]{
+		ctx:         context.WithValue(context.Background(), CtxKeyDiagnoseScanErrors, true),
+		columns:     strstr{{"id", "int64"}, {"name", "nullstring"}},
+		rows:        rows{[]any{1, nil}},
+		query:       []string{"id", "name"},
+		mapper:      StructMapper[User](),
+		expectedErr: createError(nil, "scan error", "name"),
+	})
+
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "nullstring"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, nil})
+	query := createQuery(t, []string{"id", "name"})
+
+	ctx := context.WithValue(context.Background(), CtxKeyDiagnoseScanErrors, true)
+	_, err := One(ctx, stdQ{ex}, StructMapper[User](), query)
+	if err == nil || !strings.HasPrefix(err.Error(), `scanning column "name" (type string): `) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testQuery(t, "with option", queryCase[User]{
+		columns:     strstr{{"id", "int64"}, {"name", "nullstring"}},
+		rows:        rows{[]any{1, nil}},
+		query:       []string{"id", "name"},
+		mapper:      StructMapper[User](WithScanErrorDetail()),
+		expectedErr: createError(nil, "scan error", "name"),
+	})
+
+	_, err = One(context.Background(), stdQ{ex}, StructMapper[User](WithScanErrorDetail()), query)
+	var scanErr *ScanError
+	if !errors.As(err, &scanErr) {
+		t.Fatalf("expected a scan error, got %v", err)
+	}
+
+	if scanErr.Index != 1 || scanErr.Column != "name" || scanErr.Type != reflect.TypeOf("") {
+		t.Fatalf("unexpected scan error: %+v", scanErr)
+	}
+}
+
+func TestNullAsZero(t *testing.T) {
+	testQuery(t, "null not allowed", queryCase[User]{
+		columns:     strstr{{"id", "int64"}, {"name", "nullstring"}},
+		rows:        rows{[]any{1, nil}},
+		query:       []string{"id", "name"},
+		mapper:      StructMapper[User](),
+		expectedErr: fmt.Errorf(`sql: Scan error on column index 1, name "name": %w`, errors.New("converting NULL to string is unsupported")),
+	})
+
+	testQuery(t, "null as zero", queryCase[User]{
+		columns:   strstr{{"id", "int64"}, {"name", "nullstring"}},
+		rows:      rows{[]any{1, nil}, []any{2, "bar"}},
+		query:     []string{"id", "name"},
+		mapper:    StructMapper[User](WithNullAsZero()),
+		expectOne: User{ID: 1},
+		expectAll: []User{{ID: 1}, {ID: 2, Name: "bar"}},
+	})
+}
+
+func TestNullTypeCoercion(t *testing.T) {
+	type nullable struct {
+		ID      int
+		Name    string
+		Score   float32
+		Active  bool
+		Created time.Time
+	}
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	testQuery(t, "null type coercion", queryCase[nullable]{
+		columns: strstr{
+			{"id", "nullint64"}, {"name", "nullstring"}, {"score", "nullfloat64"},
+			{"active", "nullbool"}, {"created", "nulldatetime"},
+		},
+		rows: rows{
+			[]any{nil, nil, nil, nil, nil},
+			[]any{2, "bar", 1.5, true, created},
+		},
+		query:     []string{"id", "name", "score", "active", "created"},
+		mapper:    StructMapper[nullable](WithNullTypeCoercion()),
+		expectOne: nullable{},
+		expectAll: []nullable{{}, {ID: 2, Name: "bar", Score: 1.5, Active: true, Created: created}},
+	})
+
+	type unsigned struct {
+		ID    uint
+		Count uint64
+	}
+
+	testQuery(t, "unsigned", queryCase[unsigned]{
+		columns:   strstr{{"id", "nullint64"}, {"count", "nullint64"}},
+		rows:      rows{[]any{1, nil}, []any{2, 10}},
+		query:     []string{"id", "count"},
+		mapper:    StructMapper[unsigned](WithNullTypeCoercion()),
+		expectOne: unsigned{ID: 1},
+		expectAll: []unsigned{{ID: 1}, {ID: 2, Count: 10}},
+	})
+
+	testQuery(t, "negative unsigned", queryCase[unsigned]{
+		columns:     strstr{{"id", "nullint64"}, {"count", "nullint64"}},
+		rows:        rows{[]any{1, -1}},
+		query:       []string{"id", "count"},
+		mapper:      StructMapper[unsigned](WithNullTypeCoercion()),
+		expectedErr: createError(nil, "invalid null type value", "count"),
+	})
+
+	type narrow struct {
+		Small int8
+	}
+
+	// sql.NullInt16 holds the value, but it does not fit in an int8
+	testQuery(t, "overflow", queryCase[narrow]{
+		columns:     strstr{{"small", "nullint64"}},
+		rows:        rows{[]any{300}},
+		query:       []string{"small"},
+		mapper:      StructMapper[narrow](WithNullTypeCoercion()),
+		expectedErr: createError(nil, "invalid null type value", "small"),
+	})
+}
+
+func TestReadonlyTagOption(t *testing.T) {
+	testQuery(t, "readonly", queryCase[ReadonlyUser]{
+		columns:     strstr{{"id", "int64"}, {"name", "string"}, {"computed", "string"}},
+		rows:        rows{[]any{1, "foo", "bar"}},
+		query:       []string{"id", "name", "computed"},
+		mapper:      StructMapper[ReadonlyUser](),
+		expectedErr: createError(nil, "no destination", "computed"),
+	})
+
+	testQuery(t, "readonly with unknown columns", queryCase[ReadonlyUser]{
+		ctx:       context.WithValue(context.Background(), CtxKeyAllowUnknownColumns, true),
+		columns:   strstr{{"id", "int64"}, {"name", "string"}, {"computed", "string"}},
+		rows:      rows{[]any{1, "foo", "bar"}},
+		query:     []string{"id", "name", "computed"},
+		mapper:    StructMapper[ReadonlyUser](),
+		expectOne: ReadonlyUser{ID: 1, Name: "foo"},
+		expectAll: []ReadonlyUser{{ID: 1, Name: "foo"}},
+	})
+
+	cols, err := Columns[ReadonlyUser](defaultStructMapper)
+	if err != nil {
+		t.Fatalf("couldn't get columns: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"id", "name"}, cols); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestRemainTagOption(t *testing.T) {
+	testQuery(t, "remain", queryCase[RemainUser]{
+		columns:   strstr{{"id", "int64"}, {"name", "string"}, {"age", "int64"}, {"nick", "nullstring"}},
+		rows:      rows{[]any{1, "foo", 10, "f"}, []any{2, "bar", 20, nil}},
+		query:     []string{"id", "name", "age", "nick"},
+		mapper:    StructMapper[RemainUser](),
+		expectOne: RemainUser{ID: 1, Name: "foo", Extra: map[string]any{"age": int64(10), "nick": "f"}},
+		expectAll: []RemainUser{
+			{ID: 1, Name: "foo", Extra: map[string]any{"age": int64(10), "nick": "f"}},
+			{ID: 2, Name: "bar", Extra: map[string]any{"age": int64(20), "nick": nil}},
+		},
+	})
+
+	testQuery(t, "remain without extra columns", queryCase[RemainUser]{
+		columns:   strstr{{"id", "int64"}, {"name", "string"}},
+		rows:      rows{[]any{1, "foo"}},
+		query:     []string{"id", "name"},
+		mapper:    StructMapper[RemainUser](),
+		expectOne: RemainUser{ID: 1, Name: "foo", Extra: map[string]any{}},
+		expectAll: []RemainUser{{ID: 1, Name: "foo", Extra: map[string]any{}}},
+	})
+
+	testQuery(t, "remain pointer to typed map", queryCase[RemainStringUser]{
+		columns:   strstr{{"id", "int64"}, {"name", "string"}},
+		rows:      rows{[]any{1, "foo"}},
+		query:     []string{"id", "name"},
+		mapper:    StructMapper[RemainStringUser](),
+		expectOne: RemainStringUser{ID: 1, Extra: &map[string]string{"name": "foo"}},
+		expectAll: []RemainStringUser{{ID: 1, Extra: &map[string]string{"name": "foo"}}},
+	})
+
+	testQuery(t, "remain not a map", queryCase[InvalidRemainUser]{
+		columns:     strstr{{"id", "int64"}},
+		rows:        rows{[]any{1}},
+		query:       []string{"id"},
+		mapper:      StructMapper[InvalidRemainUser](),
+		expectedErr: createError(nil, "invalid remain field", "Extra"),
+	})
+
+	cols, err := Columns[RemainUser](defaultStructMapper)
+	if err != nil {
+		t.Fatalf("couldn't get columns: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"id", "name"}, cols); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestDynamicColumns(t *testing.T) {
+	testQuery(t, "dynamic columns", queryCase[ReportRow]{
+		columns:   strstr{{"region", "string"}, {"amount_2021", "int64"}, {"amount_2022", "int64"}},
+		rows:      rows{[]any{"eu", 10, 20}, []any{"us", 30, 40}},
+		query:     []string{"region", "amount_2021", "amount_2022"},
+		mapper:    StructMapper[ReportRow](WithDynamicColumns("Amounts", "amount_")),
+		expectOne: ReportRow{Region: "eu", Amounts: map[string]int64{"2021": 10, "2022": 20}},
+		expectAll: []ReportRow{
+			{Region: "eu", Amounts: map[string]int64{"2021": 10, "2022": 20}},
+			{Region: "us", Amounts: map[string]int64{"2021": 30, "2022": 40}},
+		},
+	})
+
+	testQuery(t, "dynamic columns with unmatched column", queryCase[ReportRow]{
+		columns:     strstr{{"region", "string"}, {"amount_2021", "int64"}, {"total", "int64"}},
+		rows:        rows{[]any{"eu", 10, 10}},
+		query:       []string{"region", "amount_2021", "total"},
+		mapper:      StructMapper[ReportRow](WithDynamicColumns("Amounts", "amount_")),
+		expectedErr: createError(nil, "no destination", "total"),
+	})
+
+	testQuery(t, "dynamic columns with unmatched column allowed", queryCase[ReportRow]{
+		columns:   strstr{{"region", "string"}, {"amount_2021", "int64"}, {"total", "int64"}},
+		rows:      rows{[]any{"eu", 10, 10}},
+		query:     []string{"region", "amount_2021", "total"},
+		mapper:    StructMapper[ReportRow](WithDynamicColumns("Amounts", "amount_"), WithAllowUnknownColumns(true)),
+		expectOne: ReportRow{Region: "eu", Amounts: map[string]int64{"2021": 10}},
+		expectAll: []ReportRow{{Region: "eu", Amounts: map[string]int64{"2021": 10}}},
+	})
+
+	testQuery(t, "dynamic columns without matches", queryCase[ReportRow]{
+		columns:   strstr{{"region", "string"}},
+		rows:      rows{[]any{"eu"}},
+		query:     []string{"region"},
+		mapper:    StructMapper[ReportRow](WithDynamicColumns("Amounts", "amount_")),
+		expectOne: ReportRow{Region: "eu", Amounts: map[string]int64{}},
+		expectAll: []ReportRow{{Region: "eu", Amounts: map[string]int64{}}},
+	})
+
+	testQuery(t, "dynamic columns unknown field", queryCase[ReportRow]{
+		columns:     strstr{{"region", "string"}},
+		rows:        rows{[]any{"eu"}},
+		query:       []string{"region"},
+		mapper:      StructMapper[ReportRow](WithDynamicColumns("Totals", "amount_")),
+		expectedErr: createError(nil, "invalid dynamic columns field", "Totals"),
+	})
+
+	testQuery(t, "dynamic columns not a map", queryCase[ReportRow]{
+		columns:     strstr{{"region", "string"}},
+		rows:        rows{[]any{"eu"}},
+		query:       []string{"region"},
+		mapper:      StructMapper[ReportRow](WithDynamicColumns("Region", "amount_")),
+		expectedErr: createError(nil, "invalid dynamic columns field", "Region"),
+	})
+}
+
+func TestArgFieldQuery(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}, {"tenant_id", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name", "tenant_id"}, []any{1, "foo", 5}, []any{2, "bar", 6}, []any{3, "baz", 5})
+	query := createQuery(t, []string{"id", "name"}) + "tenant_id=?"
+
+	m := StructMapper[TenantUser](WithArgField("tenant_id", 0))
+
+	users, err := All(context.Background(), stdQ{ex}, m, query, int64(5))
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	expected := []TenantUser{
+		{ID: 1, Name: "foo", TenantID: 5},
+		{ID: 3, Name: "baz", TenantID: 5},
+	}
+	if diff := cmp.Diff(expected, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	rows, err := ex.Query(query, int64(5))
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+	defer rows.Close()
+
+	_, err = AllFromRows(context.Background(), m, rows)
+	if diff := diffErr(createError(nil, "arg index out of range", "tenant_id"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "nullstring"}, {"created_at", "datetime"}})
+	defer clean()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	insert(t, ex, []string{"id", "name", "created_at"}, []any{1, "foo, bar", createdAt}, []any{2, nil, createdAt})
+	query := createQuery(t, []string{"id", "name", "created_at"})
+
+	var buf bytes.Buffer
+	if err := EncodeCSV(context.Background(), stdQ{ex}, &buf, query); err != nil {
+		t.Fatalf("error encoding csv: %v", err)
+	}
+
+	expected := "id,name,created_at\n" +
+		"1,\"foo, bar\",2024-01-02T03:04:05Z\n" +
+		"2,,2024-01-02T03:04:05Z\n"
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	buf.Reset()
+	err := EncodeCSVWith(context.Background(), stdQ{ex}, &buf, func(val any) string {
+		if t, ok := val.(time.Time); ok {
+			return t.Format("2006-01-02")
+		}
+		return fmt.Sprint(val)
+	}, query)
+	if err != nil {
+		t.Fatalf("error encoding csv: %v", err)
+	}
+
+	expected = "id,name,created_at\n" +
+		"1,\"foo, bar\",2024-01-02\n" +
+		"2,,2024-01-02\n"
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestOnComplete(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, singleRows(1, 2, 3)...)
+	query := createQuery(t, []string{"id"})
+
+	type call struct {
+		n   int
+		err error
+	}
+
+	var calls []call
+	ctx := WithOnComplete(context.Background(), func(n int, err error) {
+		calls = append(calls, call{n: n, err: err})
+	})
+
+	check := func(t *testing.T, n int, err error) {
+		t.Helper()
+		if len(calls) != 1 {
+			t.Fatalf("expected 1 call, got %d", len(calls))
+		}
+
+		if calls[0].n != n {
+			t.Fatalf("expected %d rows, got %d", n, calls[0].n)
+		}
+
+		if diff := diffErr(err, calls[0].err); diff != "" {
+			t.Fatalf("diff: %s", diff)
+		}
+		calls = nil
+	}
+
+	t.Run("all", func(t *testing.T) {
+		if _, err := All(ctx, stdQ{ex}, SingleColumnMapper[int], query); err != nil {
+			t.Fatalf("error running query: %v", err)
+		}
+		check(t, 3, nil)
+	})
+
+	t.Run("all with error", func(t *testing.T) {
+		_, err := All(ctx, stdQ{ex}, ColumnMapper[int]("missing"), query)
+		check(t, 0, err)
+	})
+
+	t.Run("one", func(t *testing.T) {
+		if _, err := One(ctx, stdQ{ex}, SingleColumnMapper[int], query); err != nil {
+			t.Fatalf("error running query: %v", err)
+		}
+		check(t, 1, nil)
+	})
+
+	t.Run("each stopped early", func(t *testing.T) {
+		Each(ctx, stdQ{ex}, SingleColumnMapper[int], query)(func(int, error) bool {
+			return false
+		})
+		check(t, 1, nil)
+	})
+
+	t.Run("batches", func(t *testing.T) {
+		Batches(ctx, stdQ{ex}, SingleColumnMapper[int], 2, query)(func([]int, error) bool {
+			return true
+		})
+		check(t, 3, nil)
+	})
+}
+
+func TestIntoMapper(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "nullstring"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"}, []any{2, nil}, []any{3, "baz"})
+	query := createQuery(t, []string{"id", "name"})
+
+	foo, baz := "foo", "baz"
+	var dst PtrUser2
+	var got []PtrUser2
+
+	Each(context.Background(), stdQ{ex}, IntoMapper(&dst), query)(func(val PtrUser2, err error) bool {
+		if err != nil {
+			t.Fatalf("error scanning row: %v", err)
+		}
+
+		got = append(got, val)
+		return true
+	})
+
+	expected := []PtrUser2{{ID: 1, Name: &foo}, {ID: 2}, {ID: 3, Name: &baz}}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff(expected[2], dst); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	_, err := All(context.Background(), stdQ{ex}, IntoMapper[*User](nil), query)
+	if err == nil {
+		t.Fatal("expected an error for a nil destination")
+	}
+
+	var ptr *User
+	_, err = All(context.Background(), stdQ{ex}, IntoMapper(&ptr), query)
+	if err == nil {
+		t.Fatal("expected an error for a pointer type")
+	}
+}
+
+func TestScheduleScanConvert(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"}, []any{2, "bar"})
+	query := createQuery(t, []string{"id", "name"})
+
+	var order []string
+	upper := func(ctx context.Context, c cols) (BeforeFunc, func(any) (User, error)) {
+		return func(v *Row) (any, error) {
+				u := &User{}
+				v.ScheduleScanConvert("name", reflect.ValueOf(&u.Name), func(val reflect.Value) error {
+					order = append(order, "name")
+					val.Elem().SetString(strings.ToUpper(val.Elem().String()))
+					return nil
+				})
+				v.ScheduleScanConvert("id", reflect.ValueOf(&u.ID), func(val reflect.Value) error {
+					order = append(order, "id")
+					return nil
+				})
+				return u, nil
+			}, func(link any) (User, error) {
+				return *link.(*User), nil
+			}
+	}
+
+	users, err := All(context.Background(), stdQ{ex}, upper, query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]User{{ID: 1, Name: "FOO"}, {ID: 2, Name: "BAR"}}, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"id", "name", "id", "name"}, order); diff != "" {
+		t.Fatalf("conversions should run in column order: %s", diff)
+	}
+
+	failing := func(ctx context.Context, c cols) (BeforeFunc, func(any) (User, error)) {
+		return func(v *Row) (any, error) {
+				u := &User{}
+				v.ScheduleScan("id", &u.ID)
+				v.ScheduleScanConvert("name", reflect.ValueOf(&u.Name), func(reflect.Value) error {
+					return errors.New("bad name")
+				})
+				return u, nil
+			}, func(link any) (User, error) {
+				return *link.(*User), nil
+			}
+	}
+
+	_, err = All(context.Background(), stdQ{ex}, failing, query)
+	if diff := diffErr(createError(nil, "convert", "name"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestScheduleScanAll(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"})
+	query := createQuery(t, []string{"id", "name", "id"})
+
+	mapper := func(all bool) Mapper[User] {
+		return func(ctx context.Context, c cols) (BeforeFunc, func(any) (User, error)) {
+			return func(v *Row) (any, error) {
+					u := &User{}
+					if all {
+						v.ScheduleScanAll("id", &u.ID)
+					} else {
+						v.ScheduleScan("id", &u.ID)
+					}
+					v.ScheduleScan("name", &u.Name)
+					return u, nil
+				}, func(link any) (User, error) {
+					return *link.(*User), nil
+				}
+		}
+	}
+
+	_, err := One(context.Background(), stdQ{ex}, mapper(false), query)
+	if diff := diffErr(createError(nil, "no destination", "id"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	user, err := One(context.Background(), stdQ{ex}, mapper(true), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff(User{ID: 1, Name: "foo"}, user); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestScheduleScanByNames(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"created", "string"}, {"created_at", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "created", "created_at"}, []any{1, "old", "new"})
+
+	type event struct {
+		ID      int
+		Created string
+	}
+
+	mapper := func(ctx context.Context, c cols) (BeforeFunc, func(any) (event, error)) {
+		return func(v *Row) (any, error) {
+				e := &event{}
+				v.ScheduleScan("id", &e.ID)
+				v.ScheduleScanByNames([]string{"created_at", "created"}, &e.Created)
+				return e, nil
+			}, func(link any) (event, error) {
+				return *link.(*event), nil
+			}
+	}
+
+	tests := []struct {
+		name     string
+		columns  []string
+		expected event
+		err      error
+	}{
+		{
+			name:     "first present",
+			columns:  []string{"id", "created_at"},
+			expected: event{ID: 1, Created: "new"},
+		},
+		{
+			name:     "second present",
+			columns:  []string{"id", "created"},
+			expected: event{ID: 1, Created: "old"},
+		},
+		{
+			name:    "both present",
+			columns: []string{"id", "created", "created_at"},
+			err:     createError(nil, "no destination", "created"),
+		},
+		{
+			name:    "none present",
+			columns: []string{"id"},
+			err:     createError(nil, "created_at|created"),
+		},
+	}
+
+	for _, tc := range tests {
+		query := createQuery(t, tc.columns)
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := One(context.Background(), stdQ{ex}, mapper, query)
+			if diff := diffErr(tc.err, err); diff != "" {
+				t.Fatalf("diff: %s", diff)
+			}
+
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Fatalf("diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestScheduleWarner(t *testing.T) {
+	missingMod := func(ctx context.Context, c cols) (BeforeFunc, AfterMod) {
+		return func(v *Row) (any, error) {
+				v.ScheduleScan("missing", new(int))
+				return nil, nil
+			}, func(link, retrieved any) error {
+				return nil
+			}
+	}
+
+	var warned []string
+	testQuery(t, "warner", queryCase[User]{
+		columns: strstr{{"id", "int64"}, {"name", "string"}},
+		rows:    rows{[]any{1, "foo"}, []any{2, "bar"}},
+		query:   []string{"id", "name"},
+		mapper: StructMapper[User](
+			WithScheduleWarner(func(col string) { warned = append(warned, col) }),
+			WithMapperMods(missingMod),
+		),
+		expectOne: User{ID: 1, Name: "foo"},
+		expectAll: []User{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}},
+	})
+
+	// warned once for every scanned row
+	if len(warned) == 0 {
+		t.Fatal("expected warnings for the missing column")
+	}
+	for _, col := range warned {
+		if col != "missing" {
+			t.Fatalf("unexpected warning for column %q", col)
+		}
+	}
+
+	testQuery(t, "without warner", queryCase[User]{
+		columns:     strstr{{"id", "int64"}, {"name", "string"}},
+		rows:        rows{[]any{1, "foo"}},
+		query:       []string{"id", "name"},
+		mapper:      StructMapper[User](WithMapperMods(missingMod)),
+		expectedErr: createError(nil, "missing"),
+	})
+}
+
+func TestRowScanned(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"}, []any{2, "bar"})
+	query := createQuery(t, []string{"id", "name"})
+
+	mapper := func(ctx context.Context, c cols) (BeforeFunc, func(any) ([]any, error)) {
+		return func(v *Row) (any, error) {
+				if v.Scanned() != nil {
+					return nil, errors.New("scanned values before the row is scanned")
+				}
+
+				v.ScheduleScan("id", new(int))
+				return v, nil
+			}, func(link any) ([]any, error) {
+				return link.(*Row).Scanned(), nil
+			}
+	}
+
+	ctx := context.WithValue(context.Background(), CtxKeyAllowUnknownColumns, true)
+	all, err := All(ctx, stdQ{ex}, mapper, query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	// the name is not scheduled, so it is discarded with its driver type
+	expected := [][]any{{1, "foo"}, {2, "bar"}}
+	if diff := cmp.Diff(expected, all); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestRowColumns(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}, {"email", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name", "email"}, []any{1, "foo", "foo@example.com"})
+
+	type contact struct {
+		ID    int
+		Name  string
+		Email string
+	}
+
+	mapper := func(ctx context.Context, c cols) (BeforeFunc, func(any) (contact, error)) {
+		return func(v *Row) (any, error) {
+				u := &contact{}
+				v.ScheduleScan("id", &u.ID)
+				if v.HasColumn("name") {
+					v.ScheduleScan("name", &u.Name)
+				}
+				if i := v.ColumnIndex("email"); i >= 0 {
+					v.ScheduleScanByIndex(i, &u.Email)
+				}
+				return u, nil
+			}, func(link any) (contact, error) {
+				return *link.(*contact), nil
+			}
+	}
+
+	tests := []struct {
+		name     string
+		columns  []string
+		expected contact
+	}{
+		{
+			name:     "all columns",
+			columns:  []string{"id", "name", "email"},
+			expected: contact{ID: 1, Name: "foo", Email: "foo@example.com"},
+		},
+		{
+			name:     "without name",
+			columns:  []string{"email", "id"},
+			expected: contact{ID: 1, Email: "foo@example.com"},
+		},
+		{
+			name:     "only id",
+			columns:  []string{"id"},
+			expected: contact{ID: 1},
+		},
+	}
+
+	for _, tc := range tests {
+		query := createQuery(t, tc.columns)
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := One(context.Background(), stdQ{ex}, mapper, query)
+			if err != nil {
+				t.Fatalf("error running query: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Fatalf("diff: %s", diff)
+			}
+		})
+	}
+
+	row := newRow(context.Background(), []string{"id", "name", "id"})
+	if !row.HasColumn("name") || row.HasColumn("email") {
+		t.Fatalf("wrong column presence")
+	}
+
+	for name, expected := range map[string]int{"id": 0, "name": 1, "email": -1} {
+		if i := row.ColumnIndex(name); i != expected {
+			t.Fatalf("index of %q: expected %d, got %d", name, expected, i)
+		}
+	}
+}
+
+func TestRecordScheduledColumns(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}, {"email", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name", "email"}, []any{1, "foo", "foo@example.com"})
+	query := createQuery(t, []string{"id", "name", "email"})
+
+	var row *Row
+	record := func(v *Row) (any, error) {
+		row = v
+		return nil, nil
+	}
+
+	m := Mod(StructMapper[User](), func(ctx context.Context, c cols) (BeforeFunc, AfterMod) {
+		return record, func(link, retrieved any) error { return nil }
+	})
+
+	ctx := context.WithValue(context.Background(), CtxKeyAllowUnknownColumns, true)
+	ctx = context.WithValue(ctx, CtxKeyRecordScheduledColumns, true)
+
+	if _, err := One(ctx, stdQ{ex}, m, query); err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"id", "name"}, row.ScheduledColumns()); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"email"}, row.UnscheduledColumns()); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestMapResult(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"}, []any{2, "bar"})
+	query := createQuery(t, []string{"id", "name"})
+
+	names := MapResult(StructMapper[User](), func(u User) (string, error) {
+		return fmt.Sprintf("%d:%s", u.ID, u.Name), nil
+	})
+
+	all, err := All(context.Background(), stdQ{ex}, names, query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"1:foo", "2:bar"}, all); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	errBar := errors.New("bar is not allowed")
+	failing := MapResult(StructMapper[User](), func(u User) (string, error) {
+		if u.Name == "bar" {
+			return "", errBar
+		}
+		return u.Name, nil
+	})
+
+	if _, err := All(context.Background(), stdQ{ex}, failing, query); !errors.Is(err, errBar) {
+		t.Fatalf("expected the error from f, got %v", err)
+	}
+
+	var vals []string
+	var errs []error
+	Each(context.Background(), stdQ{ex}, failing, query)(func(val string, err error) bool {
+		vals = append(vals, val)
+		errs = append(errs, err)
+		return true
+	})
+
+	if diff := cmp.Diff([]string{"foo", ""}, vals); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if len(errs) != 2 || errs[0] != nil || !errors.Is(errs[1], errBar) {
+		t.Fatalf("expected the error from f for the second row, got %v", errs)
+	}
+}
+
+func TestUnexportedFields(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"})
+	query := createQuery(t, []string{"id", "name"})
+
+	_, err := One(context.Background(), stdQ{ex}, StructMapper[PrivateUser](), query)
+	if diff := diffErr(createError(nil, "no destination", "name"), err); diff != "" {
+		t.Fatalf("unexported fields should be skipped by default: %s", diff)
+	}
+
+	src, err := NewStructMapperSource(WithUnexportedFields())
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+
+	for name, m := range map[string]Mapper[PrivateUser]{
+		"regular":     CustomStructMapper[PrivateUser](src),
+		"all options": CustomStructMapper[PrivateUser](src, WithNullAsZero()),
+	} {
+		user, err := One(context.Background(), stdQ{ex}, m, query)
+		if err != nil {
+			t.Fatalf("%s: error running query: %v", name, err)
+		}
+
+		if user.ID != 1 || user.name != "foo" {
+			t.Fatalf("%s: unexpected user: %#v", name, user)
+		}
+	}
+
+	cols, err := Columns[PrivateUser](src)
+	if err != nil {
+		t.Fatalf("couldn't get columns: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"id", "name"}, cols); diff != "" {
+		t.Fatalf("untagged unexported fields should be skipped: %s", diff)
+	}
+}
+
+func TestScanRow(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"}, []any{2, "bar"})
+	query := createQuery(t, []string{"id", "name"})
+
+	rows, err := ex.Query(query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+	defer rows.Close()
+
+	// skip the first row
+	if !rows.Next() || !rows.Next() {
+		t.Fatal("expected 2 rows")
+	}
+
+	user, err := ScanRow(context.Background(), StructMapper[User](), rows)
+	if err != nil {
+		t.Fatalf("error scanning row: %v", err)
+	}
+
+	if diff := cmp.Diff(User{ID: 2, Name: "bar"}, user); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if rows.Next() {
+		t.Fatal("ScanRow should not advance the rows")
+	}
+}
+
+func TestOneOrZero(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}})
+	defer clean()
+
+	query := createQuery(t, []string{"id"})
+
+	id, found, err := OneOrZero(context.Background(), stdQ{ex}, SingleColumnMapper[int], query)
+	if err != nil {
+		t.Fatalf("error with no rows: %v", err)
+	}
+	if found || id != 0 {
+		t.Fatalf("expected no row, got %d, %t", id, found)
+	}
+
+	insert(t, ex, []string{"id"}, singleRows(5)...)
+
+	id, found, err = OneOrZero(context.Background(), stdQ{ex}, SingleColumnMapper[int], query)
+	if err != nil {
+		t.Fatalf("error scanning row: %v", err)
+	}
+	if !found || id != 5 {
+		t.Fatalf("expected 5, got %d, %t", id, found)
+	}
+
+	_, found, err = OneOrZero(context.Background(), stdQ{ex}, ColumnMapper[int]("missing"), query)
+	if err == nil || found {
+		t.Fatalf("expected an error for a missing column, got %v, %t", err, found)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+
+	if err := Validate(ctx, StructMapper[User](), []string{"id", "name"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := Validate(ctx, StructMapper[User](), []string{"id", "name", "email"})
+	if diff := diffErr(createError(nil, "no destination", "email"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	ctx = context.WithValue(ctx, CtxKeyAllowUnknownColumns, true)
+	if err := Validate(ctx, StructMapper[User](), []string{"id", "name", "email"}); err != nil {
+		t.Fatalf("unknown columns should be allowed: %v", err)
+	}
+
+	err = Validate(ctx, ColumnMapper[int]("missing"), []string{"id"})
+	if diff := diffErr(createError(nil, "missing"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	errMapper := func(context.Context, cols) (BeforeFunc, func(any) (int, error)) {
+		return ErrorMapper[int](errors.New("bad"), "some meta")
+	}
+
+	err = Validate(ctx, errMapper, []string{"id"})
+	if diff := diffErr(createError(nil, "some meta"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestAllMulti(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"}, []any{2, "bar"})
+	query := createQuery(t, []string{"id", "name"}) + ";" + createQuery(t, []string{"id"})
+
+	sets, err := AllMulti(context.Background(), stdQ{ex}, MapMapper[any], query)
+	if err != nil {
+		t.Fatalf("error scanning result sets: %v", err)
+	}
+
+	expected := [][]map[string]any{
+		{{"id": int64(1), "name": "foo"}, {"id": int64(2), "name": "bar"}},
+		{{"id": int64(1)}, {"id": int64(2)}},
+	}
+	if diff := cmp.Diff(expected, sets); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	_, err = AllMulti(context.Background(), affectedQ{stdQ: stdQ{ex}}, MapMapper[any], query)
+	if !errors.Is(err, ErrMultipleResultSetsNotSupported) {
+		t.Fatalf("expected ErrMultipleResultSetsNotSupported, got %v", err)
+	}
+}
+
+func TestResultSets(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"}, []any{2, "bar"})
+	query := createQuery(t, []string{"id", "name"}) + ";" + createQuery(t, []string{"id"})
+
+	var total int
+	ctx := WithOnComplete(context.Background(), func(n int, err error) {
+		total += n
+	})
+
+	var users []User
+	var ids []int
+	err := ResultSets(ctx, stdQ{ex}, []ResultSet{
+		Into(&users, StructMapper[User]()),
+		Into(&ids, SingleColumnMapper[int]),
+	}, query)
+	if err != nil {
+		t.Fatalf("error scanning result sets: %v", err)
+	}
+
+	if diff := cmp.Diff([]User{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}}, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]int{1, 2}, ids); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if total != 4 {
+		t.Fatalf("expected 4 rows in total, got %d", total)
+	}
+
+	err = ResultSets(context.Background(), stdQ{ex}, []ResultSet{
+		Into(&users, StructMapper[User]()),
+	}, query)
+	if err == nil {
+		t.Fatal("expected an error for an extra result set")
+	}
+}
+
+func TestStructWithRaw(t *testing.T) {
+	testQuery(t, "with raw", queryCase[WithRaw[User]]{
+		columns: strstr{{"id", "int64"}, {"name", "string"}, {"note", "nullstring"}},
+		rows:    rows{[]any{1, "foo", nil}, []any{2, "bar", "hi"}},
+		query:   []string{"id", "name", "note"},
+		mapper:  StructWithRawMapper[User](),
+		expectOne: WithRaw[User]{
+			Value: User{ID: 1, Name: "foo"},
+			Raw:   map[string]any{"id": 1, "name": "foo", "note": nil},
+		},
+		expectAll: []WithRaw[User]{
+			{
+				Value: User{ID: 1, Name: "foo"},
+				Raw:   map[string]any{"id": 1, "name": "foo", "note": nil},
+			},
+			{
+				Value: User{ID: 2, Name: "bar"},
+				Raw:   map[string]any{"id": 2, "name": "bar", "note": "hi"},
+			},
+		},
+	})
+}
+
+func TestAllWhile(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, singleRows(1, 2, 3, 4, 1)...)
+	query := createQuery(t, []string{"id"})
+
+	var calls int
+	ids, err := AllWhile(context.Background(), stdQ{ex}, SingleColumnMapper[int], query, func(id int) bool {
+		calls++
+		return id < 3
+	})
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]int{1, 2}, ids); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected the rows after the first false to be skipped, got %d calls", calls)
+	}
+
+	ids, err = AllWhile(context.Background(), stdQ{ex}, SingleColumnMapper[int], query, func(int) bool { return true })
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]int{1, 2, 3, 4, 1}, ids); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestAllTolerant(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "nullint64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, []any{1}, []any{nil}, []any{3}, []any{4}, []any{5})
+	query := createQuery(t, []string{"id"})
+
+	odd := ColumnMapperFunc("id", func(id int) (int, error) {
+		if id%2 == 0 {
+			return 0, fmt.Errorf("%d is even", id)
+		}
+		return id, nil
+	})
+
+	ids, rowErrs, err := AllTolerant(context.Background(), stdQ{ex}, odd, query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]int{1, 3, 5}, ids); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if len(rowErrs) != 2 {
+		t.Fatalf("expected 2 row errors, got %d: %v", len(rowErrs), rowErrs)
+	}
+
+	var rowErr *RowError
+	if !errors.As(rowErrs[0], &rowErr) || rowErr.Index != 1 {
+		t.Fatalf("expected an error for row 1, got %v", rowErrs[0])
+	}
+
+	if !errors.As(rowErrs[1], &rowErr) || rowErr.Index != 3 {
+		t.Fatalf("expected an error for row 3, got %v", rowErrs[1])
+	}
+
+	if diff := diffErr(createError(nil, "convert", "id"), rowErr.Err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	ids, rowErrs, err = AllTolerant(context.Background(), stdQ{ex}, ColumnMapper[int]("missing"), query)
+	if diff := diffErr(createError(nil, "missing"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if len(ids) != 0 || len(rowErrs) != 0 {
+		t.Fatalf("expected no rows or row errors for an invalid mapper, got %v and %v", ids, rowErrs)
+	}
+}
+
+func TestAllTolerantResetsFailedRow(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "nullint64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, []any{nil}, []any{2})
+	query := createQuery(t, []string{"id"})
+
+	// Only schedules a scan for the first row, which fails to scan NULL into an int
+	var calls int
+	firstOnly := func(ctx context.Context, cols []string) (BeforeFunc, func(any) (int, error)) {
+		return func(r *Row) (any, error) {
+				calls++
+				if calls > 1 {
+					return nil, nil
+				}
+
+				var id int
+				r.ScheduleScanByIndex(0, &id)
+				return &id, nil
+			}, func(link any) (int, error) {
+				return *link.(*int), nil
+			}
+	}
+
+	_, rowErrs, err := AllTolerant(context.Background(), stdQ{ex}, firstOnly, query)
+	if len(rowErrs) != 1 {
+		t.Fatalf("expected 1 row error, got %d: %v", len(rowErrs), rowErrs)
+	}
+
+	// The destination of the failed row must not be reused for the second row
+	if diff := diffErr(createError(nil, "no destination", "id"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestAllSet(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"role", "string"}, {"id", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"role", "id"},
+		[]any{"admin", 1}, []any{"user", 2}, []any{"admin", 3}, []any{"user", 4}, []any{"guest", 5})
+
+	var completed int
+	ctx := WithOnComplete(context.Background(), func(n int, err error) {
+		completed = n
+	})
+
+	roles, err := AllSet[string](ctx, stdQ{ex}, createQuery(t, []string{"role"}))
+	if err != nil {
+		t.Fatalf("error getting set: %v", err)
+	}
+
+	expected := map[string]struct{}{"admin": {}, "user": {}, "guest": {}}
+	if diff := cmp.Diff(expected, roles); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	// every row is counted, including the duplicates
+	if completed != 5 {
+		t.Fatalf("expected 5 rows, got %d", completed)
+	}
+
+	_, err = AllSet[string](context.Background(), stdQ{ex}, createQuery(t, []string{"role", "id"}))
+	if diff := diffErr(createError(nil, "wrong column count", "1", "2"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestEachIndexed(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, singleRows(5, 6, 7)...)
+	query := createQuery(t, []string{"id"})
+
+	var indexes, vals []int
+	EachIndexed(context.Background(), stdQ{ex}, SingleColumnMapper[int], query)(func(i int, val int, err error) bool {
+		if err != nil {
+			t.Fatalf("error scanning row: %v", err)
+		}
+
+		indexes = append(indexes, i)
+		vals = append(vals, val)
+		return i < 1
+	})
+
+	if diff := cmp.Diff([]int{0, 1}, indexes); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if diff := cmp.Diff([]int{5, 6}, vals); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestStructMapMapper(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}, {"name", "string"}})
+	defer clean()
+
+	insert(t, ex, []string{"id", "name"}, []any{1, "foo"}, []any{2, "bar"}, []any{1, "baz"})
+	query := createQuery(t, []string{"id", "name"})
+
+	expected := map[int]User{
+		1: {ID: 1, Name: "baz"},
+		2: {ID: 2, Name: "bar"},
+	}
+
+	// the first row consumes the whole result set
+	users, err := One(context.Background(), stdQ{ex}, StructMapMapper[int, User]("id"), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff(expected, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	all, err := All(context.Background(), stdQ{ex}, StructMapMapper[int, User]("id"), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff([]map[int]User{expected}, all); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	_, err = One(context.Background(), stdQ{ex}, StructMapMapper[int, User]("missing"), query)
+	if diff := diffErr(createError(nil, "unknown key column", "missing"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	small, err := One(context.Background(), stdQ{ex}, StructMapMapper[int8, User]("id"), query)
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff(map[int8]User{1: {ID: 1, Name: "baz"}, 2: {ID: 2, Name: "bar"}}, small); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	// the id field is an int, which is never converted to a string
+	_, err = One(context.Background(), stdQ{ex}, StructMapMapper[string, User]("id"), query)
+	if diff := diffErr(createError(nil, "invalid key type", "id"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	// the key is read from the *any destination of the value
+	ids, err := AllMap[int64, any](context.Background(), stdQ{ex}, "id", "id", createQuery(t, []string{"id"}))
+	if err != nil {
+		t.Fatalf("error running query: %v", err)
+	}
+
+	if diff := cmp.Diff(map[int64]any{1: int64(1), 2: int64(2)}, ids); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	insert(t, ex, []string{"id", "name"}, []any{300, "qux"})
+
+	_, err = One(context.Background(), stdQ{ex}, StructMapMapper[int8, User]("id"), query)
+	if diff := diffErr(createError(nil, "invalid key", "id"), err); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	ex, clean := createDB(t, strstr{{"id", "int64"}})
+	defer clean()
+
+	insert(t, ex, []string{"id"}, singleRows(1, 2, 3)...)
+	query := createQuery(t, []string{"id"})
+
+	t.Run("all", func(t *testing.T) {
+		rows, err := ex.Query(query)
+		if err != nil {
+			t.Fatalf("error running query: %v", err)
+		}
+		defer rows.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = AllFromRows(ctx, SingleColumnMapper[int], rows)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("each", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var vals []int
+		var lastErr error
+		Each(ctx, stdQ{ex}, SingleColumnMapper[int], query)(func(val int, err error) bool {
+			if err != nil {
+				lastErr = err
+				return false
+			}
+
+			vals = append(vals, val)
+			cancel()
+			return true
+		})
+
+		if diff := cmp.Diff([]int{1}, vals); diff != "" {
+			t.Fatalf("diff: %s", diff)
+		}
+
+		if !errors.Is(lastErr, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", lastErr)
+		}
+	})
 }
@@ -0,0 +1,341 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Joined holds a parent and a child mapped from the same row
+type Joined[P, C any] struct {
+	Parent P
+	Child  C
+}
+
+// JoinMapper combines a parent and a child mapper into a single mapper that maps
+// both values from the same row. It is typically used for rows from a JOIN
+// and the results can then be grouped with [GroupBy].
+//
+// If both mappers schedule a scan for the same column, the column is only scanned once
+// and the value is copied to the other destination before the after functions run.
+// This works if both destinations have the same type or one of them is *any,
+// such as the destinations for discarded columns. Otherwise, an error is returned.
+// Conversions scheduled with [Row.ScheduleScanConvert] run for both destinations
+func JoinMapper[P, C any](parent Mapper[P], child Mapper[C]) Mapper[Joined[P, C]] {
+	return func(ctx context.Context, c cols) (BeforeFunc, func(any) (Joined[P, C], error)) {
+		parentBefore, parentAfter := parent(ctx, c)
+		childBefore, childAfter := child(ctx, c)
+
+		return func(v *Row) (any, error) {
+				parentLink, err := parentBefore(v)
+				if err != nil {
+					return nil, err
+				}
+
+				dests := append([]reflect.Value(nil), v.scanDestinations...)
+				converters := append([]func(reflect.Value) error(nil), v.scanConverters...)
+
+				childLink, err := childBefore(v)
+				if err != nil {
+					return nil, err
+				}
+
+				shared, err := shareDestinations(v, dests, converters)
+				if err != nil {
+					return nil, err
+				}
+
+				return [3]any{parentLink, childLink, shared}, nil
+			}, func(link any) (Joined[P, C], error) {
+				var j Joined[P, C]
+				links := link.([3]any)
+
+				for _, s := range links[2].([]sharedDest) {
+					if err := s.copy(); err != nil {
+						return j, err
+					}
+				}
+
+				p, err := parentAfter(links[0])
+				if err != nil {
+					return j, err
+				}
+
+				c, err := childAfter(links[1])
+				if err != nil {
+					return j, err
+				}
+
+				j.Parent, j.Child = p, c
+				return j, nil
+			}
+	}
+}
+
+// sharedDest is a destination that gets the value of a column scanned into another destination
+type sharedDest struct {
+	column  string
+	from    reflect.Value
+	to      reflect.Value
+	convert func(reflect.Value) error
+}
+
+func (s sharedDest) copy() error {
+	s.to.Elem().Set(s.from.Elem())
+
+	if s.convert != nil {
+		if err := s.convert(s.to); err != nil {
+			return createError(err, "convert", s.column)
+		}
+	}
+
+	return nil
+}
+
+// shareDestinations finds the columns whose destination in dests was replaced
+// and shares the scanned value between both destinations.
+// converters are the conversions of dests
+func shareDestinations(v *Row, dests []reflect.Value, converters []func(reflect.Value) error) ([]sharedDest, error) {
+	var shared []sharedDest
+	anyPtr := typeOf[*any]()
+
+	for i, dest := range dests {
+		current := v.scanDestinations[i]
+		if dest == zeroValue || current == dest {
+			continue
+		}
+
+		var destConvert, currentConvert func(reflect.Value) error
+		if converters != nil {
+			destConvert = converters[i]
+		}
+		if v.scanConverters != nil {
+			currentConvert = v.scanConverters[i]
+		}
+
+		switch {
+		case dest.Type() == current.Type() || dest.Type() == anyPtr:
+			shared = append(shared, sharedDest{
+				column:  v.columns[i],
+				from:    current,
+				to:      dest,
+				convert: destConvert,
+			})
+
+		case current.Type() == anyPtr:
+			// Scan into the typed destination instead
+			v.scanDestinations[i] = dest
+			if v.scanConverters != nil {
+				v.scanConverters[i] = nil
+			}
+			if destConvert != nil {
+				if v.scanConverters == nil {
+					v.scanConverters = make([]func(reflect.Value) error, len(v.columns))
+				}
+				v.scanConverters[i] = destConvert
+			}
+
+			shared = append(shared, sharedDest{
+				column:  v.columns[i],
+				from:    dest,
+				to:      current,
+				convert: currentConvert,
+			})
+
+		default:
+			err := fmt.Errorf("column %s is scanned into both %s and %s", v.columns[i], dest.Type(), current.Type())
+			return nil, createError(err, "conflicting destinations", v.columns[i])
+		}
+	}
+
+	return shared, nil
+}
+
+// Collect2 maps every row of the query with both mappers and returns the results
+// in two slices of the same length. The mappers are combined with [JoinMapper]
+// so each column only needs a destination from one of them
+func Collect2[A, B any](ctx context.Context, exec Queryer, ma Mapper[A], mb Mapper[B], query string, args ...any) ([]A, []B, error) {
+	rows, err := All(ctx, exec, JoinMapper(ma, mb), query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	as := make([]A, len(rows))
+	bs := make([]B, len(rows))
+	for i, row := range rows {
+		as[i], bs[i] = row.Parent, row.Child
+	}
+
+	return as, bs, nil
+}
+
+// Collect3 works like [Collect2] but with three mappers
+func Collect3[A, B, C any](ctx context.Context, exec Queryer, ma Mapper[A], mb Mapper[B], mc Mapper[C], query string, args ...any) ([]A, []B, []C, error) {
+	rows, err := All(ctx, exec, JoinMapper(JoinMapper(ma, mb), mc), query, args...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	as := make([]A, len(rows))
+	bs := make([]B, len(rows))
+	cs := make([]C, len(rows))
+	for i, row := range rows {
+		as[i], bs[i], cs[i] = row.Parent.Parent, row.Parent.Child, row.Child
+	}
+
+	return as, bs, cs, nil
+}
+
+// GroupBy groups the rows by the key of the parent and calls add for every
+// child to attach it to its parent.
+//
+// Rows with the same key do not need to be next to each other.
+// The parents are returned in the order in which their key was first seen
+// and the parent value from the first row with that key is kept.
+// The children are added in the order of the rows.
+//
+// For a LEFT JOIN, the child may be the zero value when there is no match,
+// so add should check for this
+//
+//	joined, err := scan.All(ctx, db, scan.JoinMapper(
+//	    scan.StructMapper[User](),
+//	    scan.StructMapper[*Post](scan.WithStructTagPrefix("post.")),
+//	), query)
+//	users := scan.GroupBy(joined,
+//	    func(u User) int { return u.ID },
+//	    func(u *User, p *Post) {
+//	        if p != nil {
+//	            u.Posts = append(u.Posts, *p)
+//	        }
+//	    },
+//	)
+func GroupBy[K comparable, P, C any](rows []Joined[P, C], key func(P) K, add func(*P, C)) []P {
+	parents := make([]P, 0)
+	index := make(map[K]int)
+
+	for _, row := range rows {
+		k := key(row.Parent)
+		i, ok := index[k]
+		if !ok {
+			i = len(parents)
+			index[k] = i
+			parents = append(parents, row.Parent)
+		}
+
+		add(&parents[i], row.Child)
+	}
+
+	return parents
+}
+
+// GroupByKeys runs the query and groups the rows into parents by the values of the key columns,
+// calling add for every child to attach it to its parent. This is useful to hydrate a one-to-many
+// relation from a JOIN when the parent has a composite key, without scanning the rows into a slice first.
+// The parent and child mappers are combined with [JoinMapper], so they can also scan the key columns.
+//
+// Rows with the same key do not need to be next to each other.
+// The parents are returned in the order in which their key was first seen
+// and the parent value from the first row with that key is kept.
+// The children are added in the order of the rows. NULL values are part of the key
+// like any other value.
+//
+// Every parent is kept in memory until the query is done, along with the values of its key.
+// For very large results, order the query by the key columns and use [Each] instead
+// to handle one parent at a time.
+//
+//	users, err := scan.GroupByKeys(ctx, db,
+//	    scan.StructMapper[User](),
+//	    scan.StructMapper[Post](scan.WithStructTagPrefix("post_")),
+//	    []string{"org_id", "id"},
+//	    func(u *User, p Post) { u.Posts = append(u.Posts, p) },
+//	    query,
+//	)
+func GroupByKeys[P, C any](ctx context.Context, exec Queryer, parent Mapper[P], child Mapper[C], keyCols []string, add func(*P, C), query string, args ...any) ([]P, error) {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		onComplete(ctx)(0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return GroupByKeysFromRows(withQueryArgs(ctx, args), parent, child, keyCols, add, rows)
+}
+
+// GroupByKeysFromRows works like [GroupByKeys] with the given [Rows]
+func GroupByKeysFromRows[P, C any](ctx context.Context, parent Mapper[P], child Mapper[C], keyCols []string, add func(*P, C), rows Rows) (_ []P, err error) {
+	var n int
+	defer func() { onComplete(ctx)(n, err) }()
+
+	v, err := wrapRows(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	m := JoinMapper(JoinMapper(parent, child), compositeKeyMapper(keyCols))
+	before, after := m(ctx, v.columnsCopy())
+
+	parents := make([]P, 0)
+	index := make(map[string]int)
+
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		row, err := scanOneRow(v, before, after)
+		if err != nil {
+			return nil, err
+		}
+
+		i, ok := index[row.Child]
+		if !ok {
+			i = len(parents)
+			index[row.Child] = i
+			parents = append(parents, row.Parent.Parent)
+		}
+
+		add(&parents[i], row.Parent.Child)
+		n++
+	}
+
+	return parents, rows.Err()
+}
+
+// compositeKeyMapper maps the values of the key columns into a comparable key
+func compositeKeyMapper(keyCols []string) Mapper[string] {
+	return func(ctx context.Context, c cols) (BeforeFunc, func(any) (string, error)) {
+		for _, keyCol := range keyCols {
+			if !hasColumn(c, keyCol) {
+				err := fmt.Errorf("key column %q is not in the result", keyCol)
+				return ErrorMapper[string](err, "unknown key column", keyCol)
+			}
+		}
+
+		return func(v *Row) (any, error) {
+				vals := make([]any, len(keyCols))
+				for i, keyCol := range keyCols {
+					v.ScheduleScan(keyCol, &vals[i])
+				}
+
+				return vals, nil
+			}, func(link any) (string, error) {
+				return compositeKey(link.([]any)), nil
+			}
+	}
+}
+
+// compositeKey encodes the values of the key columns into a string.
+// The values are quoted so that different values cannot have the same key
+func compositeKey(vals []any) string {
+	var b strings.Builder
+	for _, val := range vals {
+		if bytes, ok := val.([]byte); ok {
+			val = string(bytes)
+		}
+
+		fmt.Fprintf(&b, "%T:%q;", val, fmt.Sprint(val))
+	}
+
+	return b.String()
+}
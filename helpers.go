@@ -1,7 +1,119 @@
 package scan
 
-import "reflect"
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+var timeType = typeOf[time.Time]()
 
 func typeOf[T any]() reflect.Type {
 	return reflect.TypeOf((*T)(nil)).Elem()
 }
+
+// isTimeType reports if the type is time.Time or a type defined from it
+// such as `type Date time.Time`
+func isTimeType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && typ.ConvertibleTo(timeType)
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return isIntKind(k) || isUintKind(k) || isFloatKind(k)
+}
+
+// convertNumber converts between integer, unsigned integer and float values.
+// Unlike [reflect.Value.Convert], it returns an error if the value does not fit in the type
+// or would lose precision, such as a fraction converted to an integer
+func convertNumber(val reflect.Value, typ reflect.Type) (reflect.Value, error) {
+	out := reflect.New(typ).Elem()
+	lossy := func() (reflect.Value, error) {
+		return zeroValue, fmt.Errorf("value %v of type %s does not fit in %s", val, val.Type(), typ)
+	}
+
+	switch {
+	case isIntKind(val.Kind()):
+		n := val.Int()
+		switch {
+		case isIntKind(typ.Kind()):
+			if out.OverflowInt(n) {
+				return lossy()
+			}
+			out.SetInt(n)
+		case isUintKind(typ.Kind()):
+			if n < 0 || out.OverflowUint(uint64(n)) {
+				return lossy()
+			}
+			out.SetUint(uint64(n))
+		case isFloatKind(typ.Kind()):
+			out.SetFloat(float64(n))
+			if f := out.Float(); f >= math.MaxInt64 || int64(f) != n {
+				return lossy()
+			}
+		default:
+			return zeroValue, fmt.Errorf("cannot convert %s to %s", val.Type(), typ)
+		}
+
+	case isUintKind(val.Kind()):
+		n := val.Uint()
+		switch {
+		case isIntKind(typ.Kind()):
+			if n > math.MaxInt64 || out.OverflowInt(int64(n)) {
+				return lossy()
+			}
+			out.SetInt(int64(n))
+		case isUintKind(typ.Kind()):
+			if out.OverflowUint(n) {
+				return lossy()
+			}
+			out.SetUint(n)
+		case isFloatKind(typ.Kind()):
+			out.SetFloat(float64(n))
+			if f := out.Float(); f >= math.MaxUint64 || uint64(f) != n {
+				return lossy()
+			}
+		default:
+			return zeroValue, fmt.Errorf("cannot convert %s to %s", val.Type(), typ)
+		}
+
+	case isFloatKind(val.Kind()):
+		f := val.Float()
+		switch {
+		case isIntKind(typ.Kind()):
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || out.OverflowInt(int64(f)) {
+				return lossy()
+			}
+			out.SetInt(int64(f))
+		case isUintKind(typ.Kind()):
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || out.OverflowUint(uint64(f)) {
+				return lossy()
+			}
+			out.SetUint(uint64(f))
+		case isFloatKind(typ.Kind()):
+			if out.OverflowFloat(f) {
+				return lossy()
+			}
+			out.SetFloat(f)
+		default:
+			return zeroValue, fmt.Errorf("cannot convert %s to %s", val.Type(), typ)
+		}
+
+	default:
+		return zeroValue, fmt.Errorf("cannot convert %s to %s", val.Type(), typ)
+	}
+
+	return out, nil
+}
@@ -3,9 +3,12 @@ package scan
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"math/big"
 	"math/rand"
+	"net/netip"
 	"reflect"
 	"strconv"
 	"strings"
@@ -67,6 +70,12 @@ type PtrUser2 struct {
 	*PtrTimestamps
 }
 
+type PtrPtrUser struct {
+	ID    **int
+	Name  **string
+	Owner **Owner
+}
+
 type UserWithTimestamps struct {
 	User
 	*Timestamps
@@ -85,6 +94,236 @@ type Tagged struct {
 	Exclude int    `db:"-" custom:"-"`
 }
 
+type PartlyTagged struct {
+	ID        int `db:"user_id"`
+	FirstName string
+	LastName  string
+}
+
+type JSONSettings struct {
+	Theme string `json:"theme"`
+}
+
+type JSONUser struct {
+	ID       int
+	Metadata map[string]any
+	Settings *JSONSettings `db:"settings,json"`
+}
+
+// Counter is an immutable type that returns a new copy when modified
+type Counter int
+
+func (c Counter) Add(n int) Counter {
+	return c + Counter(n)
+}
+
+type CounterUser struct {
+	ID     int
+	Visits Counter
+}
+
+type TimeStringUser struct {
+	ID        int
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+type Date time.Time
+
+type DateUser struct {
+	ID          int64
+	Birthday    Date
+	Anniversary *Date
+}
+
+type EpochUser struct {
+	ID        int
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+	SeenAt    int64
+}
+
+type PaddedUser struct {
+	ID   int
+	Code string
+	Name *string
+	Note string
+}
+
+type HstoreUser struct {
+	ID    int
+	Attrs map[string]string
+	Tags  *map[string]*string
+}
+
+type HostUser struct {
+	ID      int
+	Addr    netip.Addr
+	Gateway *netip.Addr
+}
+
+type AmountUser struct {
+	ID      int
+	Balance big.Int
+	Limit   *big.Int
+	Ratio   big.Rat
+	Rate    *big.Rat
+}
+
+type SensitiveUser struct {
+	ID   int
+	Name string
+	SSN  string
+}
+
+type TenantUser struct {
+	ID       int
+	Name     string
+	TenantID int64
+}
+
+type SplitUser struct {
+	ID    int
+	Tags  []string `db:"tags,split=,"`
+	Roles []string `db:",split= | "`
+}
+
+type BadSplitUser struct {
+	Tags string `db:",split=,"`
+}
+
+type SliceUser struct {
+	ID    int
+	Tags  []string
+	Data  []byte
+	Blogs []Blog
+}
+
+type Named interface {
+	GetName() string
+}
+
+type StringName string
+
+func (s StringName) GetName() string {
+	return string(s)
+}
+
+type NamedUser struct {
+	ID   int
+	Name Named
+}
+
+// GeoValue implements sql.Scanner with a value receiver
+type GeoValue struct {
+	Lat, Lng float64
+}
+
+func (g GeoValue) Scan(any) error {
+	return nil
+}
+
+func (g GeoValue) Value() (driver.Value, error) {
+	return nil, nil
+}
+
+// GeoPointer implements sql.Scanner with a pointer receiver
+type GeoPointer struct {
+	Lat, Lng float64
+}
+
+func (g *GeoPointer) Scan(any) error {
+	return nil
+}
+
+func (g *GeoPointer) Value() (driver.Value, error) {
+	return nil, nil
+}
+
+type GeoMatrix struct {
+	ValueValue     GeoValue
+	PointerValue   *GeoValue
+	ValuePointer   GeoPointer
+	PointerPointer *GeoPointer
+}
+
+type PlaceUser struct {
+	ID       int
+	Location GeoPointer
+}
+
+type JoinedPost struct {
+	ID    int
+	Title string
+}
+
+type UserWithPost struct {
+	ID   int
+	Name string
+	Post *JoinedPost
+}
+
+type DuplicateUser struct {
+	ID      int
+	Name    string
+	Display string `db:"name"`
+}
+
+type Owner struct {
+	ID   int
+	Name string
+}
+
+type Pet struct {
+	ID int
+}
+
+type OwnerPet struct {
+	Owner `db:",prefix=owner_"`
+	Pet   `db:",prefix=pet_"`
+}
+
+type OrderedUser struct {
+	ID int
+	*Timestamps
+	Owner *Owner
+	Name  string
+	Pet   `db:",prefix=pet_"`
+}
+
+type PrivateUser struct {
+	ID      int
+	name    string `db:"name"`
+	private string
+}
+
+type ReadonlyUser struct {
+	ID       int
+	Name     string
+	Computed string `db:"computed,readonly"`
+}
+
+type RemainUser struct {
+	ID    int
+	Name  string
+	Extra map[string]any `db:",remain"`
+}
+
+type RemainStringUser struct {
+	ID    int
+	Extra *map[string]string `db:",remain"`
+}
+
+type ReportRow struct {
+	Region  string
+	Amounts map[string]int64
+}
+
+type InvalidRemainUser struct {
+	ID    int
+	Extra []string `db:",remain"`
+}
+
 type ScannableUser struct {
 	ID   int
 	Name string
@@ -125,6 +364,29 @@ func (d typeConverter) ValueFromDestination(val reflect.Value) reflect.Value {
 	return val.Elem().FieldByName("V").Elem().Elem()
 }
 
+// columnTypeConverter only wraps the "name" column
+type columnTypeConverter struct{}
+
+func (d columnTypeConverter) TypeToDestination(typ reflect.Type) reflect.Value {
+	return reflect.New(typ)
+}
+
+func (d columnTypeConverter) TypeToDestinationCtx(name string, typ reflect.Type) reflect.Value {
+	if name != "name" {
+		return reflect.New(typ)
+	}
+
+	return typeConverter{}.TypeToDestination(typ)
+}
+
+func (d columnTypeConverter) ValueFromDestination(val reflect.Value) reflect.Value {
+	if w, ok := val.Interface().(*wrapper); ok {
+		return reflect.ValueOf(w.V).Elem()
+	}
+
+	return val.Elem()
+}
+
 func toPtr[T any](v T) *T {
 	return &v
 }
@@ -0,0 +1,121 @@
+package scan
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseHstore parses the text representation of a PostgreSQL hstore value,
+// such as `"a"=>"1", b=>NULL`. Keys and values may be double quoted, in which case
+// a backslash escapes the next character. An unquoted NULL value is a nil value.
+// This is used for columns set with [WithHstoreColumns]
+func ParseHstore(s string) (map[string]*string, error) {
+	p := hstoreParser{s: s}
+	m := make(map[string]*string)
+
+	p.skipSpace()
+	for !p.done() {
+		key, _, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if err := p.expect("=>"); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+
+		val, quoted, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+
+		if !quoted && strings.EqualFold(val, "NULL") {
+			m[key] = nil
+		} else {
+			m[key] = &val
+		}
+
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+	}
+
+	return m, nil
+}
+
+type hstoreParser struct {
+	s   string
+	pos int
+}
+
+func (p *hstoreParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *hstoreParser) skipSpace() {
+	for !p.done() && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *hstoreParser) expect(token string) error {
+	if !strings.HasPrefix(p.s[p.pos:], token) {
+		return fmt.Errorf("invalid hstore: expected %q at position %d", token, p.pos)
+	}
+
+	p.pos += len(token)
+	return nil
+}
+
+// word reads a quoted or unquoted key or value
+func (p *hstoreParser) word() (string, bool, error) {
+	if p.done() {
+		return "", false, fmt.Errorf("invalid hstore: unexpected end at position %d", p.pos)
+	}
+
+	if p.s[p.pos] != '"' {
+		start := p.pos
+		for !p.done() && !unicode.IsSpace(rune(p.s[p.pos])) && p.s[p.pos] != ',' && !strings.HasPrefix(p.s[p.pos:], "=>") {
+			p.pos++
+		}
+
+		if p.pos == start {
+			return "", false, fmt.Errorf("invalid hstore: expected a key or value at position %d", p.pos)
+		}
+
+		return p.s[start:p.pos], false, nil
+	}
+
+	var b strings.Builder
+	start := p.pos
+	p.pos++
+
+	for !p.done() {
+		c := p.s[p.pos]
+		p.pos++
+
+		switch c {
+		case '"':
+			return b.String(), true, nil
+		case '\\':
+			if p.done() {
+				break
+			}
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", false, fmt.Errorf("invalid hstore: unterminated quote at position %d", start)
+}
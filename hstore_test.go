@@ -0,0 +1,43 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseHstore(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	cases := []struct {
+		name     string
+		input    string
+		expected map[string]*string
+	}{
+		{name: "empty", input: "", expected: map[string]*string{}},
+		{name: "quoted", input: `"a"=>"1", "b"=>"2"`, expected: map[string]*string{"a": str("1"), "b": str("2")}},
+		{name: "unquoted", input: `a=>1,b => 2`, expected: map[string]*string{"a": str("1"), "b": str("2")}},
+		{name: "null", input: `"a"=>NULL, "b"=>"NULL"`, expected: map[string]*string{"a": nil, "b": str("NULL")}},
+		{name: "escaped", input: `"say \"hi\""=>"back\\slash", "a,b"=>"c=>d"`, expected: map[string]*string{`say "hi"`: str(`back\slash`), "a,b": str("c=>d")}},
+		{name: "spaces", input: `  "a b" => " c "  `, expected: map[string]*string{"a b": str(" c ")}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseHstore(tc.input)
+			if err != nil {
+				t.Fatalf("error parsing hstore: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Fatalf("diff: %s", diff)
+			}
+		})
+	}
+
+	for _, input := range []string{`"a"`, `"a"=>`, `"a"=>"1" "b"=>"2"`, `"a"=>"1`, `=>"1"`} {
+		if _, err := ParseHstore(input); err == nil {
+			t.Fatalf("expected an error parsing %q", input)
+		}
+	}
+}
@@ -22,6 +22,12 @@ type Rows interface {
 	Err() error
 }
 
+// RowsAffecter can optionally be implemented by [Rows] to report the number of rows
+// affected by the query. It is used by [AllWithRowsAffected]
+type RowsAffecter interface {
+	RowsAffected() (int64, error)
+}
+
 type TypeConverter interface {
 	// TypeToDestination is called with the expected type of the column
 	// it is expected to return a pointer to the desired value to scan into
@@ -33,11 +39,26 @@ type TypeConverter interface {
 	ValueFromDestination(reflect.Value) reflect.Value
 }
 
+// TypeConverterCtx can optionally be implemented by a [TypeConverter]
+// so that conversions can be decided per column.
+// If implemented, TypeToDestinationCtx is used instead of TypeToDestination
+type TypeConverterCtx interface {
+	// TypeToDestinationCtx is called with the column name and the expected type of the column
+	// it is expected to return a pointer to the desired value to scan into
+	// the returned destination is directly scanned into
+	TypeToDestinationCtx(name string, typ reflect.Type) reflect.Value
+}
+
 // RowValidator is called with pointer to all the values from a row
 // to determine if the row is valid
 // if it is not, the zero type for that row is returned
 type RowValidator = func(cols []string, vals []reflect.Value) bool
 
 type StructMapperSource interface {
+	// ClearCache removes all the cached struct mappings
+	ClearCache()
+
 	getMapping(reflect.Type) (mapping, error)
+	interfaceFactories() map[reflect.Type]func() reflect.Value
+	columnTransformer() func(string) string
 }
@@ -2,9 +2,11 @@ package scan
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 type (
@@ -23,9 +25,22 @@ func (v visited) copy() visited {
 
 type mapinfo struct {
 	name      string
+	path      []string
 	position  []int
 	init      [][]int
 	isPointer bool
+	isJSON    bool
+	split     string
+
+	// positions of the scannable structs this field is a fallback for
+	fallbackOf [][]int
+
+	// the field captures the columns not claimed by other fields
+	remain bool
+
+	// the field is bound to the column at index with [WithPositionalFallback]
+	positional bool
+	index      int
 }
 
 type mapping []mapinfo
@@ -39,6 +54,79 @@ func (m mapping) cols() []string {
 	return cols
 }
 
+// fieldColumns returns the columns of the fields, without
+// the fields that collect the remaining columns
+func (m mapping) fieldColumns() []string {
+	cols := make([]string, 0, len(m))
+	for _, info := range m {
+		if !info.remain {
+			cols = append(cols, info.name)
+		}
+	}
+
+	return cols
+}
+
+func (m mapping) has(name string) bool {
+	for _, info := range m {
+		if info.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasAnyPosition reports if any of the fields is at one of the positions
+func (m mapping) hasAnyPosition(positions [][]int) bool {
+	for _, info := range m {
+		for _, pos := range positions {
+			if samePosition(info.position, pos) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func samePosition(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// remainField returns the field with the remain tag option, if any
+func (m mapping) remainField() (mapinfo, bool) {
+	for _, info := range m {
+		if info.remain {
+			return info, true
+		}
+	}
+
+	return mapinfo{}, false
+}
+
+// withSeparator returns a copy of the mapping with the column names
+// rebuilt from the field paths using the given separator
+func (m mapping) withSeparator(sep string) mapping {
+	m2 := make(mapping, len(m))
+	for i, info := range m {
+		info.name = strings.Join(info.path, sep)
+		m2[i] = info
+	}
+
+	return m2
+}
+
 // Mapper is a function that return the mapping functions.
 // Any expensive operation, like reflection should be done outside the returned
 // function.
@@ -89,10 +177,15 @@ func (m *MappingError) Unwrap() error {
 	return m.cause
 }
 
-// Error implements the error interface
+// Error implements the error interface.
+// If there is no wrapped error, the metadata is used for the message
 func (m *MappingError) Error() string {
 	if m.cause == nil {
-		return ""
+		if len(m.meta) == 0 {
+			return "mapping error"
+		}
+
+		return "mapping error: " + strings.Join(m.meta, ", ")
 	}
 
 	return m.cause.Error()
@@ -128,6 +221,158 @@ func ColumnMapper[T any](name string) func(ctx context.Context, c cols) (before
 	}
 }
 
+// Map a column by name, scanning it into Src and converting it to Dst with conv.
+// This is useful for types such as enums without implementing [sql.Scanner].
+// An error returned by conv is returned for the row
+func ColumnMapperFunc[Src, Dst any](name string, conv func(Src) (Dst, error)) func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (Dst, error)) {
+	return func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (Dst, error)) {
+		return func(v *Row) (any, error) {
+				var src Src
+				v.ScheduleScan(name, &src)
+				return &src, nil
+			}, func(v any) (Dst, error) {
+				dst, err := conv(*(v.(*Src)))
+				if err != nil {
+					return dst, createError(err, "convert", name)
+				}
+
+				return dst, nil
+			}
+	}
+}
+
+// Map a column by its position, starting from 0.
+// This is useful for expression columns such as count(*) whose name depends on the driver
+func ColumnMapperIndex[T any](index int) func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (T, error)) {
+	return func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (T, error)) {
+		if index < 0 || index >= len(c) {
+			err := fmt.Errorf("column index %d out of range for %d columns", index, len(c))
+			return ErrorMapper[T](err, "column index out of range")
+		}
+
+		return func(v *Row) (any, error) {
+				var t T
+				v.ScheduleScanByIndex(index, &t)
+				return &t, nil
+			}, func(v any) (T, error) {
+				return *(v.(*T)), nil
+			}
+	}
+}
+
+// Pair holds the values of two columns of a row. It is returned by [PairMapper]
+type Pair[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// Map two columns by name into a [Pair]. This is useful for key/value tables.
+// Use [ToMap] to turn the pairs into a map
+func PairMapper[K, V any](keyCol, valCol string) func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (Pair[K, V], error)) {
+	return func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (Pair[K, V], error)) {
+		return func(v *Row) (any, error) {
+				var p Pair[K, V]
+				v.ScheduleScan(keyCol, &p.Key)
+				v.ScheduleScan(valCol, &p.Value)
+				return &p, nil
+			}, func(v any) (Pair[K, V], error) {
+				return *(v.(*Pair[K, V])), nil
+			}
+	}
+}
+
+// ToMap turns pairs into a map. If multiple pairs have the same key, the last one is kept
+//
+//	pairs, err := scan.All(ctx, exec, scan.PairMapper[string, int]("key", "value"), query)
+//	settings := scan.ToMap(pairs)
+func ToMap[K comparable, V any](pairs []Pair[K, V]) map[K]V {
+	m := make(map[K]V, len(pairs))
+	for _, p := range pairs {
+		m[p.Key] = p.Value
+	}
+
+	return m
+}
+
+// Tuple2 holds the values of two columns of a row. It is returned by [TupleMapper2]
+type Tuple2[A, B any] struct {
+	V1 A
+	V2 B
+}
+
+// Tuple3 holds the values of three columns of a row. It is returned by [TupleMapper3]
+type Tuple3[A, B, C any] struct {
+	V1 A
+	V2 B
+	V3 C
+}
+
+// Map two columns into a [Tuple2]. The columns are bound by name if names are given,
+// otherwise the query must return exactly two columns which are bound by position.
+// This gives typed values for fixed-shape queries without using []any
+//
+//	scan.All(ctx, exec, scan.TupleMapper2[string, int](), "SELECT name, count(*) FROM users GROUP BY name")
+func TupleMapper2[A, B any](names ...string) func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (Tuple2[A, B], error)) {
+	return func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (Tuple2[A, B], error)) {
+		if err := checkTuple(2, names, c); err != nil {
+			return ErrorMapper[Tuple2[A, B]](err)
+		}
+
+		return func(v *Row) (any, error) {
+				var t Tuple2[A, B]
+				scheduleTuple(v, names, &t.V1, &t.V2)
+				return &t, nil
+			}, func(v any) (Tuple2[A, B], error) {
+				return *(v.(*Tuple2[A, B])), nil
+			}
+	}
+}
+
+// Map three columns into a [Tuple3]. The columns are bound in the same way as [TupleMapper2]
+func TupleMapper3[A, B, C any](names ...string) func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (Tuple3[A, B, C], error)) {
+	return func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (Tuple3[A, B, C], error)) {
+		if err := checkTuple(3, names, c); err != nil {
+			return ErrorMapper[Tuple3[A, B, C]](err)
+		}
+
+		return func(v *Row) (any, error) {
+				var t Tuple3[A, B, C]
+				scheduleTuple(v, names, &t.V1, &t.V2, &t.V3)
+				return &t, nil
+			}, func(v any) (Tuple3[A, B, C], error) {
+				return *(v.(*Tuple3[A, B, C])), nil
+			}
+	}
+}
+
+// checkTuple returns an error if the names or the columns
+// do not match the number of values in the tuple
+func checkTuple(n int, names []string, c cols) error {
+	switch {
+	case len(names) > 0 && len(names) != n:
+		err := fmt.Errorf("Expected %d column names for the tuple but got %d", n, len(names))
+		return createError(err, "wrong tuple column count", strconv.Itoa(n), strconv.Itoa(len(names)))
+
+	case len(names) == 0 && len(c) != n:
+		err := fmt.Errorf("Expected %d columns but got %d columns", n, len(c))
+		return createError(err, "wrong column count", strconv.Itoa(n), strconv.Itoa(len(c)))
+	}
+
+	return nil
+}
+
+// scheduleTuple schedules the scans for the values of a tuple
+// by name if names are given, or by position otherwise
+func scheduleTuple(v *Row, names []string, dests ...any) {
+	for i, dest := range dests {
+		if len(names) > 0 {
+			v.ScheduleScan(names[i], dest)
+		} else {
+			v.ScheduleScanByIndex(i, dest)
+		}
+	}
+}
+
 // Maps each row into []any in the order
 func SliceMapper[T any](ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) ([]T, error)) {
 	return func(v *Row) (any, error) {
@@ -166,3 +411,175 @@ func MapMapper[T any](ctx context.Context, c cols) (before func(*Row) (any, erro
 			return row, nil
 		}
 }
+
+// JSONRowMapper returns a mapper that encodes each row as a JSON object keyed by the column names.
+// The values are scanned the same way as [MapMapper] and encoded with encoding/json,
+// so NULL values are encoded as null and []byte values are base64 encoded.
+// Note that some drivers return text columns as []byte
+func JSONRowMapper() Mapper[json.RawMessage] {
+	return func(ctx context.Context, c cols) (BeforeFunc, func(any) (json.RawMessage, error)) {
+		before, after := MapMapper[any](ctx, c)
+
+		return before, func(link any) (json.RawMessage, error) {
+			row, err := after(link)
+			if err != nil {
+				return nil, err
+			}
+
+			encoded, err := json.Marshal(row)
+			if err != nil {
+				return nil, createError(err, "invalid json")
+			}
+
+			return encoded, nil
+		}
+	}
+}
+
+// Same as [MapMapper] but the columns that are NULL are left out of the map.
+// This means that the keys of the map can be different for every row.
+// Every column is scanned into a pointer to detect NULL values,
+// so it does not fail on NULL values even if T is not a pointer
+func MapMapperOmitNull[T any](ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (map[string]T, error)) {
+	return func(v *Row) (any, error) {
+			row := make([]*T, len(c))
+
+			for index, name := range c {
+				v.ScheduleScan(name, &row[index])
+			}
+
+			return row, nil
+		}, func(v any) (map[string]T, error) {
+			row := make(map[string]T, len(c))
+			slice := v.([]*T)
+			for index, name := range c {
+				if slice[index] == nil {
+					continue
+				}
+				row[name] = *slice[index]
+			}
+
+			return row, nil
+		}
+}
+
+// Same as [MapMapper] but returns an error if the column names are not unique
+// instead of keeping only the last value
+func MapMapperStrict[T any](ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (map[string]T, error)) {
+	seen := make(map[string]struct{}, len(c))
+	for _, name := range c {
+		if _, ok := seen[name]; ok {
+			err := fmt.Errorf("duplicate column %q", name)
+			return ErrorMapper[map[string]T](err, "duplicate column", name)
+		}
+		seen[name] = struct{}{}
+	}
+
+	return MapMapper[T](ctx, c)
+}
+
+type keyed[K comparable, T any] struct {
+	key K
+	val T
+}
+
+// keyedMapper wraps a mapper to also retrieve the value of the key column.
+// If the mapper already scans the key column, the value is read from its destination
+// since a column can only be scanned into one destination
+func keyedMapper[K comparable, T any](keyCol string, m Mapper[T]) Mapper[keyed[K, T]] {
+	return func(ctx context.Context, c cols) (BeforeFunc, func(any) (keyed[K, T], error)) {
+		index := -1
+		for i, name := range c {
+			if name == keyCol {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			err := fmt.Errorf("key column %q is not in the result", keyCol)
+			return ErrorMapper[keyed[K, T]](err, "unknown key column", keyCol)
+		}
+
+		before, after := m(ctx, c)
+		keyType := typeOf[K]()
+
+		return func(v *Row) (any, error) {
+				link, err := before(v)
+				if err != nil {
+					return nil, err
+				}
+
+				dest := v.scanDestinations[index]
+				if dest == zeroValue {
+					dest = reflect.New(keyType)
+					v.ScheduleScanx(keyCol, dest)
+				}
+
+				return [2]any{link, dest}, nil
+			}, func(link any) (keyed[K, T], error) {
+				var k keyed[K, T]
+				links := link.([2]any)
+
+				val, err := after(links[0])
+				if err != nil {
+					return k, err
+				}
+
+				key := links[1].(reflect.Value).Elem()
+				for key.Kind() == reflect.Pointer || key.Kind() == reflect.Interface {
+					if key.IsNil() {
+						return k, createError(fmt.Errorf("key column %q is NULL", keyCol), "null key", keyCol)
+					}
+					key = key.Elem()
+				}
+
+				key, err = convertKey(keyCol, key, keyType)
+				if err != nil {
+					return k, err
+				}
+
+				k.key = key.Interface().(K)
+				k.val = val
+				return k, nil
+			}
+	}
+}
+
+// convertKey converts the value of the key column to the key type.
+// Assignable values are used as they are. Numbers are only converted if they fit in the key type,
+// and only strings and byte slices are converted to strings, so an integer never becomes a rune
+func convertKey(keyCol string, key reflect.Value, keyType reflect.Type) (reflect.Value, error) {
+	typeErr := func() (reflect.Value, error) {
+		err := fmt.Errorf("cannot use key column %q of type %s as %s", keyCol, key.Type(), keyType)
+		return zeroValue, createError(err, "invalid key type", keyCol)
+	}
+
+	isBytes := key.Kind() == reflect.Slice && key.Type().Elem().Kind() == reflect.Uint8
+
+	switch {
+	case key.Type().AssignableTo(keyType):
+		return key, nil
+
+	case isNumberKind(key.Kind()) && isNumberKind(keyType.Kind()):
+		converted, err := convertNumber(key, keyType)
+		if err != nil {
+			return zeroValue, createError(fmt.Errorf("key column %q: %w", keyCol, err), "invalid key", keyCol)
+		}
+		return converted, nil
+
+	case keyType.Kind() == reflect.String:
+		if key.Kind() != reflect.String && !isBytes {
+			return typeErr()
+		}
+		return key.Convert(keyType), nil
+
+	case isNumberKind(key.Kind()), isNumberKind(keyType.Kind()), key.Kind() == reflect.String, isBytes:
+		return typeErr()
+
+	case key.Type().ConvertibleTo(keyType):
+		return key.Convert(keyType), nil
+	}
+
+	return typeErr()
+}
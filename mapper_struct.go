@@ -2,13 +2,50 @@ package scan
 
 import (
 	"context"
+	"database/sql/driver"
 	"fmt"
+	"math/big"
 	"reflect"
+	"strings"
+	"time"
+	"unsafe"
 )
 
 // CtxKeyAllowUnknownColumns makes it possible to allow unknown columns using the context
 var CtxKeyAllowUnknownColumns contextKey = "allow unknown columns"
 
+// CtxKeyDiagnoseScanErrors makes it possible to find the column that failed to scan.
+// When enabled using the context and scanning a row fails, the columns are scanned again
+// one at a time and the error is wrapped with the name and type of the failing column.
+// This only has a cost when scanning fails
+var CtxKeyDiagnoseScanErrors contextKey = "diagnose scan errors"
+
+// CtxKeyRecordScheduledColumns makes the [Row] record which columns had a scheduled scan.
+// When enabled using the context, they are available from [Row.ScheduledColumns]
+// and [Row.UnscheduledColumns] after each row is scanned.
+// This is useful to find columns that are silently discarded when unknown columns are allowed
+var CtxKeyRecordScheduledColumns contextKey = "record scheduled columns"
+
+// CtxKeyColumnAliases makes it possible to set column aliases for a single query using the context.
+// The value should be a map[string]string in the same format as [WithColumnAliases].
+// It is only used by struct mappers and takes precedence over the aliases set with [WithColumnAliases].
+// Columns without an alias are matched as usual
+var CtxKeyColumnAliases contextKey = "column aliases"
+
+// CtxKeyFieldOverrides is used to set values in the context that override scanned values.
+// The value should be a map[string]any with the column names of the fields as the keys.
+// It is only used by struct mappers created with [WithContextFieldOverrides]
+var CtxKeyFieldOverrides contextKey = "field overrides"
+
+// used by [AllWithMatched] to record the columns matched by the struct mapper
+var ctxKeyMatchedColumns contextKey = "matched columns"
+
+// used by the query functions to make the query args available to the mapper
+var ctxKeyQueryArgs contextKey = "query args"
+
+// used by [WithOnComplete] to set the function called when a query is done
+var ctxKeyOnComplete contextKey = "on complete"
+
 // Uses reflection to create a mapping function for a struct type
 // using the default options
 func StructMapper[T any](opts ...MappingOption) Mapper[T] {
@@ -18,22 +55,180 @@ func StructMapper[T any](opts ...MappingOption) Mapper[T] {
 // Uses reflection to create a mapping function for a struct type
 // using with custom options
 func CustomStructMapper[T any](src StructMapperSource, optMod ...MappingOption) Mapper[T] {
+	opts := structMapperOptions(src, optMod)
+
+	mod := func(ctx context.Context, c cols) (func(*Row) (any, error), func(any) (T, error)) {
+		return structMapperFrom[T](ctx, c, src, opts)
+	}
+
+	return withMapperMods(mod, opts)
+}
+
+// StructMapMapper maps the rows to a map of T keyed by the value of keyCol.
+// Each row is mapped to T with the struct mapper and the given options.
+//
+// Since it aggregates, it consumes the whole result set: the first row that is
+// mapped also scans every following row into the same map. This means [One]
+// returns the map of all the rows, and [All] a slice with that single map.
+// If multiple rows have the same key, the last one wins.
+//
+// The key column can also be mapped to a field. Numbers are only converted to K
+// if they fit, and only strings and byte slices are converted to a string K
+//
+//	// map[int]User{...}
+//	users, err := scan.One(ctx, exec, scan.StructMapMapper[int, User]("id"), query)
+func StructMapMapper[K comparable, T any](keyCol string, opts ...MappingOption) Mapper[map[K]T] {
+	m := keyedMapper[K](keyCol, StructMapper[T](opts...))
+
+	return func(ctx context.Context, c cols) (BeforeFunc, func(any) (map[K]T, error)) {
+		before, after := m(ctx, c)
+
+		return func(v *Row) (any, error) {
+				link, err := before(v)
+				if err != nil {
+					return nil, err
+				}
+
+				return [2]any{v, link}, nil
+			}, func(link any) (map[K]T, error) {
+				links := link.([2]any)
+				v := links[0].(*Row)
+
+				one, err := after(links[1])
+				if err != nil {
+					return nil, err
+				}
+
+				results := map[K]T{one.key: one.val}
+				if v.r == nil {
+					return results, nil
+				}
+
+				for v.r.Next() {
+					one, err := scanOneRow(v, before, after)
+					if err != nil {
+						return nil, err
+					}
+
+					results[one.key] = one.val
+				}
+
+				return results, v.r.Err()
+			}
+	}
+}
+
+// MapperFromSource works like [CustomStructMapper] but gets the mapping of T from
+// the source immediately, so an invalid type returns an error right away.
+// The returned mapper reuses the mapping instead of getting it from the source for every query.
+// It also returns the columns that T maps to in the same order as [Columns],
+// which is useful for tools that generate queries for the mapper
+func MapperFromSource[T any](src StructMapperSource, optMod ...MappingOption) (Mapper[T], []string, error) {
+	typ := typeOf[T]()
+
+	isPointer, err := checks(typ)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := src.getMapping(typ)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := structMapperOptions(src, optMod)
+	mod := Mapper[T](mapperFromMapping[T](m, typ, isPointer, opts))
+
+	columns := m
+	if opts.columnSeparator != "" {
+		columns = m.withSeparator(opts.columnSeparator)
+	}
+
+	return withMapperMods(mod, opts), columns.fieldColumns(), nil
+}
+
+// structMapperOptions applies the options and adds the settings from the source
+func structMapperOptions(src StructMapperSource, optMod []MappingOption) mappingOptions {
 	opts := mappingOptions{}
 	for _, o := range optMod {
 		o(&opts)
 	}
 
-	mod := func(ctx context.Context, c cols) (func(*Row) (any, error), func(any) (T, error)) {
-		return structMapperFrom[T](ctx, c, src, opts)
+	opts.columnTransformer = src.columnTransformer()
+
+	if srcFactories := src.interfaceFactories(); len(srcFactories) > 0 {
+		factories := make(map[reflect.Type]func() reflect.Value, len(srcFactories)+len(opts.factories))
+		for typ, f := range srcFactories {
+			factories[typ] = f
+		}
+		for typ, f := range opts.factories {
+			factories[typ] = f
+		}
+		opts.factories = factories
+	}
+
+	return opts
+}
+
+// withMapperMods wraps the mapper with the mods set in the options
+func withMapperMods[T any](mod Mapper[T], opts mappingOptions) Mapper[T] {
+	mods := opts.mapperMods
+	if opts.scheduleWarner != nil {
+		mods = append(mods[:len(mods):len(mods)], scheduleWarnerMod(opts.scheduleWarner))
+	}
+	if opts.allowUnknown || opts.unknownWarner != nil {
+		mods = append(mods[:len(mods):len(mods)], allowUnknownMod(opts.unknownWarner))
 	}
 
-	if len(opts.mapperMods) > 0 {
-		mod = Mod(mod, opts.mapperMods...)
+	if len(mods) > 0 {
+		mod = Mod(mod, mods...)
+	}
+
+	if opts.panicRecovery {
+		mod = withPanicRecovery(mod)
 	}
 
 	return mod
 }
 
+// withPanicRecovery converts panics while creating the mapper or
+// in its before and after functions into a [MappingError]
+func withPanicRecovery[T any](m Mapper[T]) Mapper[T] {
+	return func(ctx context.Context, c cols) (before func(*Row) (any, error), after func(any) (T, error)) {
+		defer func() {
+			if r := recover(); r != nil {
+				before, after = ErrorMapper[T](panicError(r), "panic")
+			}
+		}()
+
+		mBefore, mAfter := m(ctx, c)
+
+		return func(v *Row) (_ any, err error) {
+				defer recoverError(&err)
+				return mBefore(v)
+			}, func(v any) (_ T, err error) {
+				defer recoverError(&err)
+				return mAfter(v)
+			}
+	}
+}
+
+// recoverError sets err to a [MappingError] if there is a panic.
+// It must be deferred directly
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = createError(panicError(r), "panic")
+	}
+}
+
+func panicError(r any) error {
+	if err, ok := r.(error); ok {
+		return fmt.Errorf("recovered from panic: %w", err)
+	}
+
+	return fmt.Errorf("recovered from panic: %v", r)
+}
+
 func structMapperFrom[T any](ctx context.Context, c cols, s StructMapperSource, opts mappingOptions) (func(*Row) (any, error), func(any) (T, error)) {
 	typ := typeOf[T]()
 
@@ -50,6 +245,203 @@ func structMapperFrom[T any](ctx context.Context, c cols, s StructMapperSource,
 	return mapperFromMapping[T](mapping, typ, isPointer, opts)(ctx, c)
 }
 
+// Columns returns the names of all the columns that the struct type T maps to
+// using the given source. This is useful to build a SELECT statement
+// that matches the struct.
+//
+// The columns are always in the order the fields are declared in,
+// with the fields of nested and embedded structs in place of the struct field,
+// so the generated statements are the same across runs
+func Columns[T any](src StructMapperSource) ([]string, error) {
+	typ := typeOf[T]()
+	if _, err := checks(typ); err != nil {
+		return nil, err
+	}
+
+	m, err := src.getMapping(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.fieldColumns(), nil
+}
+
+// WithRaw holds a mapped value together with the raw values of all the columns of the row
+type WithRaw[T any] struct {
+	Value T
+	Raw   map[string]any
+}
+
+// StructWithRawMapper works like [StructMapper] but also returns the values of all the
+// columns in a map keyed by the column names. This is useful for auditing and logging.
+//
+// Every column is only scanned once. For columns that are mapped to fields, the raw value
+// is read from the destination used by the struct mapper with pointers dereferenced,
+// so it has the type of the field and not necessarily the type returned by the driver.
+// NULL values are nil if the destination is a pointer.
+// Columns that are not mapped to any field are also scanned, so they are never unknown
+func StructWithRawMapper[T any](opts ...MappingOption) Mapper[WithRaw[T]] {
+	m := StructMapper[T](opts...)
+
+	return func(ctx context.Context, c cols) (BeforeFunc, func(any) (WithRaw[T], error)) {
+		before, after := m(ctx, c)
+
+		return func(v *Row) (any, error) {
+				link, err := before(v)
+				if err != nil {
+					return nil, err
+				}
+
+				dests := make([]reflect.Value, len(c))
+				for i, name := range c {
+					dests[i] = v.scanDestinations[i]
+					if dests[i] == zeroValue {
+						dests[i] = reflect.New(typeOf[any]())
+						v.ScheduleScanx(name, dests[i])
+					}
+				}
+
+				return [2]any{link, dests}, nil
+			}, func(link any) (WithRaw[T], error) {
+				var w WithRaw[T]
+				links := link.([2]any)
+
+				dests := links[1].([]reflect.Value)
+				w.Raw = make(map[string]any, len(c))
+				for i, name := range c {
+					val := dests[i].Elem()
+					for val.Kind() == reflect.Pointer && !val.IsNil() {
+						val = val.Elem()
+					}
+
+					if val.Kind() == reflect.Pointer || !val.IsValid() {
+						w.Raw[name] = nil
+						continue
+					}
+
+					w.Raw[name] = val.Interface()
+				}
+
+				val, err := after(links[0])
+				if err != nil {
+					return w, err
+				}
+
+				w.Value = val
+				return w, nil
+			}
+	}
+}
+
+// IntoMapper returns a mapper that scans every row into the fields of dst
+// instead of allocating a new struct for each row. This is meant for processing
+// rows one at a time with [Each] or [Cursor] without any per-row struct allocations.
+//
+// Every field with a matching column is overwritten for each row, but fields without
+// a column keep their values. The returned value is a copy of *dst, so any slices, maps or pointers in it
+// may be overwritten by the next row and should be copied if they need to be kept
+func IntoMapper[T any](dst *T) Mapper[T] {
+	return func(ctx context.Context, c cols) (func(*Row) (any, error), func(any) (T, error)) {
+		if dst == nil {
+			return ErrorMapper[T](fmt.Errorf("nil destination passed to IntoMapper"))
+		}
+
+		typ := typeOf[T]()
+		isPointer, err := checks(typ)
+		if err != nil {
+			return ErrorMapper[T](err)
+		}
+
+		if isPointer {
+			return ErrorMapper[T](fmt.Errorf("IntoMapper requires a struct type, got %s", typ))
+		}
+
+		m, err := defaultStructMapper.getMapping(typ)
+		if err != nil {
+			return ErrorMapper[T](err)
+		}
+
+		filtered, err := filterColumns(ctx, c, m, mappingOptions{
+			columnTransformer: defaultStructMapper.columnTransformer(),
+		})
+		if err != nil {
+			return ErrorMapper[T](err)
+		}
+
+		row := reflect.ValueOf(dst).Elem()
+
+		return func(v *Row) (any, error) {
+				for _, info := range filtered {
+					initPointers(row, info.init)
+
+					fv := fieldByIndex(row, info.position)
+					v.ScheduleScanx(info.name, fv.Addr())
+				}
+
+				return nil, nil
+			}, func(any) (T, error) {
+				return *dst, nil
+			}
+	}
+}
+
+// fieldByIndex works like [reflect.Value.FieldByIndex] but the returned field
+// can also be set if it is unexported. Unexported fields are only in the mapping
+// if they are allowed with [WithUnexportedFields]
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		for i > 0 && v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+		if !v.CanSet() && v.CanAddr() {
+			v = reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+		}
+	}
+
+	return v
+}
+
+// initPointers allocates the pointer fields at the given positions if they are nil.
+// Every level of a multi-level pointer such as **T is allocated
+func initPointers(row reflect.Value, inits [][]int) {
+	for _, index := range inits {
+		pv := fieldByIndex(row, index)
+		for pv.Kind() == reflect.Pointer {
+			if pv.IsNil() {
+				pv.Set(reflect.New(pv.Type().Elem()))
+			}
+			pv = pv.Elem()
+		}
+	}
+}
+
+// fieldOfType works like [reflect.Type.FieldByIndex] on the struct type
+// but it also goes through multi-level pointers such as **T
+func fieldOfType(typ reflect.Type, index []int) reflect.StructField {
+	var field reflect.StructField
+	for _, x := range index {
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+
+		field = typ.Field(x)
+		typ = field.Type
+	}
+
+	return field
+}
+
+// structType returns the struct type, dereferencing it if it is a pointer
+func structType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Pointer {
+		return typ.Elem()
+	}
+
+	return typ
+}
+
 // Check if there are any errors, and returns if it is a pointer or not
 func checks(typ reflect.Type) (bool, error) {
 	if typ == nil {
@@ -74,10 +466,77 @@ func checks(typ reflect.Type) (bool, error) {
 }
 
 type mappingOptions struct {
-	typeConverter   TypeConverter
-	rowValidator    RowValidator
-	mapperMods      []MapperMod
-	structTagPrefix string
+	// how the columns are matched to the fields
+	structTagPrefix    string
+	prefixFallthrough  bool
+	columnAliases      map[string]string
+	positionalFallback bool
+	columnSeparator    string
+	dynamicColumns     []dynamicColumns
+	argFields          map[string]int
+	ctxOverrides       bool
+
+	// how each field is scanned, see [fieldStrategies]
+	typeConverter TypeConverter
+	factories     map[reflect.Type]func() reflect.Value
+	builders      map[string]func(current, scanned any) any
+	timeLayouts   map[string]string
+	epochColumns  map[string]time.Duration
+	jsonColumns   map[string]bool
+	hstoreColumns map[string]bool
+	trimColumns   columnSet
+	textColumns   columnSet
+	bigColumns    columnSet
+	nullAsZero    bool
+	nullCoercion  bool
+	nilOnAllNull  []string
+
+	// applied to the whole row or mapper
+	rowValidator   RowValidator
+	mapperMods     []MapperMod
+	scheduleWarner func(col string)
+	unknownWarner  func(cols []string)
+	allowUnknown   bool
+	panicRecovery  bool
+
+	// set from the source
+	columnTransformer func(string) string
+}
+
+// columnSet holds the columns an option such as [WithTrimStringColumns] is used for.
+// If the option is set without columns, it is used for every field of a matching type
+type columnSet struct {
+	enabled bool
+	columns map[string]bool
+}
+
+// add enables the option for the given columns, or for every matching field if there are none
+func (c *columnSet) add(columns []string) {
+	c.enabled = true
+	if len(columns) == 0 {
+		c.columns = nil
+		return
+	}
+
+	if c.columns == nil {
+		c.columns = make(map[string]bool)
+	}
+	for _, column := range columns {
+		c.columns[column] = true
+	}
+}
+
+// includes reports if the option is used for the column.
+// matches is only used if the option was set without columns
+func (c columnSet) includes(name string, matches bool) bool {
+	switch {
+	case !c.enabled:
+		return false
+	case c.columns == nil:
+		return matches
+	default:
+		return c.columns[name]
+	}
 }
 
 // MappingeOption is a function type that changes how the mapper is generated
@@ -92,8 +551,27 @@ func WithRowValidator(rv RowValidator) MappingOption {
 	}
 }
 
+// WithRowValidatorMap works like [WithRowValidator] but the validator receives
+// the values of the row keyed by column name. The map is built for every row,
+// only when this validator is set. It replaces any validator set with WithRowValidator
+//
+//	scan.WithRowValidatorMap(func(vals map[string]reflect.Value) bool {
+//	    return vals["id"].Elem().Int() != 0
+//	})
+func WithRowValidatorMap(rv func(map[string]reflect.Value) bool) MappingOption {
+	return WithRowValidator(func(cols []string, vals []reflect.Value) bool {
+		m := make(map[string]reflect.Value, len(cols))
+		for i, col := range cols {
+			m[col] = vals[i]
+		}
+
+		return rv(m)
+	})
+}
+
 // TypeConverter sets the [TypeConverter] for the struct mapper
-// it is called to modify the type of a column and get the original value back
+// it is called to modify the type of a column and get the original value back.
+// If the converter also implements [TypeConverterCtx], it receives the column name
 func WithTypeConverter(tc TypeConverter) MappingOption {
 	return func(opt *mappingOptions) {
 		opt.typeConverter = tc
@@ -107,6 +585,478 @@ func WithStructTagPrefix(prefix string) MappingOption {
 	}
 }
 
+// WithPrefixFallthrough makes the mapper also use columns without the prefix set with
+// [WithStructTagPrefix]. A column without the prefix is only used for a field
+// when there is no prefixed column for it.
+// This is useful when joining a prefixed table with shared columns that are not prefixed
+func WithPrefixFallthrough() MappingOption {
+	return func(opt *mappingOptions) {
+		opt.prefixFallthrough = true
+	}
+}
+
+// WithColumnAliases maps columns of the result to the names that the fields are mapped to.
+// The keys are the column names in the result and the values are the mapped names,
+// which are used as is without the prefix set with [WithStructTagPrefix].
+// This is useful when the columns of a generated query cannot be renamed.
+//
+//	scan.WithColumnAliases(map[string]string{"usr_nm": "name"})
+//
+// The mapper returns an error if multiple columns are aliased to the same name
+func WithColumnAliases(aliases map[string]string) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.columnAliases = aliases
+	}
+}
+
+// WithPositionalFallback binds the columns of the result that have an empty name,
+// such as computed expressions with some drivers, to the field at the same position
+// among the fields of the struct. The fields are in the same order as [Columns].
+//
+// This is a best-effort fallback: the position of a column only matches the field
+// if the query selects every column in the order of the fields.
+// A column is not bound if its position has no field or the field is already
+// matched by a named column, after the aliases and the column transformer.
+// The bound columns are scanned by their index, the column names are not changed
+func WithPositionalFallback() MappingOption {
+	return func(opt *mappingOptions) {
+		opt.positionalFallback = true
+	}
+}
+
+// checkAliases returns an error if multiple columns are aliased to the same name
+func checkAliases(aliases map[string]string) error {
+	seen := make(map[string]string, len(aliases))
+	for col, alias := range aliases {
+		if other, ok := seen[alias]; ok {
+			if other > col {
+				other, col = col, other
+			}
+
+			err := fmt.Errorf("columns %s and %s are both aliased to %s", other, col, alias)
+			return createError(err, "duplicate column alias", alias)
+		}
+		seen[alias] = col
+	}
+
+	return nil
+}
+
+// checkMapField returns an error if the field that
+// collects columns is not a map with string keys
+func checkMapField(typ reflect.Type, info mapinfo, meta string) error {
+	field := fieldOfType(typ, info.position)
+	ft := field.Type
+	if info.isPointer {
+		ft = ft.Elem()
+	}
+
+	if ft.Kind() != reflect.Map || ft.Key().Kind() != reflect.String {
+		err := fmt.Errorf("field %s collects columns but is of type %s", field.Name, field.Type)
+		return createError(err, meta, field.Name)
+	}
+
+	return nil
+}
+
+type dynamicColumns struct {
+	field  string
+	prefix string
+}
+
+// WithDynamicColumns collects the columns that start with prefix into the map field
+// with the given Go field name, keyed by the rest of the column name.
+// This is useful for pivot queries with columns like amount_2021 and amount_2022.
+// The field must be a map with string keys and the values are scanned into the map's value type.
+//
+// Only columns that are not mapped to any other field are collected,
+// and they are matched before the field with the remain tag option
+func WithDynamicColumns(fieldName, prefix string) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.dynamicColumns = append(opt.dynamicColumns, dynamicColumns{
+			field:  fieldName,
+			prefix: prefix,
+		})
+	}
+}
+
+// dynamicFields finds the map fields set with WithDynamicColumns
+func dynamicFields(typ reflect.Type, m mapping, dynamic []dynamicColumns) ([]mapinfo, error) {
+	infos := make([]mapinfo, len(dynamic))
+	for i, d := range dynamic {
+		field, ok := structType(typ).FieldByName(d.field)
+		if !ok {
+			err := fmt.Errorf("no field %s in %s", d.field, structType(typ))
+			return nil, createError(err, "invalid dynamic columns field", d.field)
+		}
+
+		var found bool
+		for _, info := range m {
+			if samePosition(info.position, field.Index) {
+				infos[i], found = info, true
+				break
+			}
+		}
+
+		if !found {
+			err := fmt.Errorf("field %s is not mapped", d.field)
+			return nil, createError(err, "invalid dynamic columns field", d.field)
+		}
+
+		if err := checkMapField(typ, infos[i], "invalid dynamic columns field"); err != nil {
+			return nil, err
+		}
+	}
+
+	return infos, nil
+}
+
+// WithInterfaceFactory sets constructors for interface typed fields.
+// Each constructor should return a pointer to a concrete value which is scanned into
+// and then set back into the interface field. If the pointer itself does not
+// implement the interface, the value it points to is used instead.
+// They take precedence over the factories registered on the source with [WithSourceInterfaceFactory].
+//
+// A field with an interface type that has methods and no factory returns an error,
+// unless the mapper has a [TypeConverter]. Fields of empty interfaces such as any
+// are scanned into directly
+func WithInterfaceFactory(factories map[reflect.Type]func() reflect.Value) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.factories = factories
+	}
+}
+
+// WithColumnSeparatorOverride changes the separator used for the column names of
+// nested struct fields for this mapper only.
+// If not set, the separator of the [StructMapperSource] is used
+func WithColumnSeparatorOverride(separator string) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.columnSeparator = separator
+	}
+}
+
+// WithAllowUnknownColumns allows columns in the result that do not map to any
+// field of the struct. Such columns, and duplicates of the mapped columns, are scanned and discarded.
+// This has the same effect as setting [CtxKeyAllowUnknownColumns] in the context
+func WithAllowUnknownColumns(allow bool) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.allowUnknown = allow
+	}
+}
+
+// WithWarnUnknownColumns allows columns in the result that do not map to any
+// field of the struct like [WithAllowUnknownColumns], but calls fn with the names
+// of the discarded columns. fn is called at most once per query, when the first row is scanned.
+// This gives visibility into typos in column names without failing the query
+func WithWarnUnknownColumns(fn func(cols []string)) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.unknownWarner = fn
+	}
+}
+
+// allowUnknownMod makes the row discard the columns without a destination,
+// including duplicate columns, and report them to fn if it is not nil
+func allowUnknownMod(fn func(cols []string)) MapperMod {
+	return func(ctx context.Context, c cols) (BeforeFunc, AfterMod) {
+		return func(v *Row) (any, error) {
+				v.allowUnknown = true
+				v.unknownWarner = fn
+				return nil, nil
+			}, func(link, retrieved any) error {
+				return nil
+			}
+	}
+}
+
+// WithScheduleWarner sets a function that is called with the name of every column
+// that a scan was scheduled for but is not present in the result.
+// Instead of failing the row with an unknown column error, these scans are skipped
+// and fn is called once for each of them after the row is scanned.
+// This is useful for debugging custom mappers and mods
+func WithScheduleWarner(fn func(col string)) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.scheduleWarner = fn
+	}
+}
+
+func scheduleWarnerMod(fn func(col string)) MapperMod {
+	return func(ctx context.Context, c cols) (BeforeFunc, AfterMod) {
+		return func(v *Row) (any, error) {
+				v.scheduleWarner = fn
+				return nil, nil
+			}, func(link, retrieved any) error {
+				return nil
+			}
+	}
+}
+
+// WithPanicRecovery recovers from panics in the mapper, such as reflection panics
+// from an unexpected value, and returns them as a [MappingError] for the row instead.
+// This keeps a malformed mapping from crashing a server.
+// By default, panics are not recovered so they surface normally
+func WithPanicRecovery() MappingOption {
+	return func(opt *mappingOptions) {
+		opt.panicRecovery = true
+	}
+}
+
+// WithScanErrorDetail diagnoses scan errors for this mapper in the same way as
+// setting [CtxKeyDiagnoseScanErrors] in the context. When scanning a row fails,
+// the columns are scanned again one at a time and the error has a [*ScanError]
+// with the index and name of the failing column. This only has a cost when scanning fails
+func WithScanErrorDetail() MappingOption {
+	return func(opt *mappingOptions) {
+		opt.mapperMods = append(opt.mapperMods, diagnoseMod)
+	}
+}
+
+func diagnoseMod(ctx context.Context, c cols) (BeforeFunc, AfterMod) {
+	return func(v *Row) (any, error) {
+			v.diagnose = true
+			return nil, nil
+		}, func(link, retrieved any) error {
+			return nil
+		}
+}
+
+// WithJSONColumns scans the given columns as JSON and unmarshals them into the struct field.
+// NULL values leave the field as the zero value.
+// Since nested structs are mapped field by field, struct fields that
+// should be unmarshaled from JSON should use the `json` tag option instead.
+// For example:
+//
+//	Settings Settings `db:"settings,json"`
+func WithJSONColumns(columns ...string) MappingOption {
+	return func(opt *mappingOptions) {
+		if opt.jsonColumns == nil {
+			opt.jsonColumns = make(map[string]bool, len(columns))
+		}
+		for _, c := range columns {
+			opt.jsonColumns[c] = true
+		}
+	}
+}
+
+// WithFieldBuilder sets a function used to build the value of the field mapped to the column.
+// The column is scanned into an `any` and the builder is called with the
+// current value of the field and the scanned value.
+// The returned value is then set as the value of the field.
+// This is useful for immutable types that are updated by returning a new copy
+func WithFieldBuilder(column string, build func(current, scanned any) any) MappingOption {
+	return func(opt *mappingOptions) {
+		if opt.builders == nil {
+			opt.builders = make(map[string]func(current, scanned any) any)
+		}
+		opt.builders[column] = build
+	}
+}
+
+// WithTimeLayout scans the column as a string and parses it with the given layout
+// into a time.Time or *time.Time field.
+// This is useful for drivers that return datetime columns as strings.
+// NULL values leave the field as the zero value
+func WithTimeLayout(column string, layout string) MappingOption {
+	return func(opt *mappingOptions) {
+		if opt.timeLayouts == nil {
+			opt.timeLayouts = make(map[string]string)
+		}
+		opt.timeLayouts[column] = layout
+	}
+}
+
+// WithEpochTimeColumns converts the given columns between Unix epoch seconds and time.Time.
+// For time.Time or *time.Time fields, the column is scanned as an integer and converted to a time in UTC.
+// For integer fields, the column is scanned as a time and converted to the number of seconds.
+// The integer field can be signed or unsigned, and an error is returned if the epoch does not fit in it.
+// NULL values leave the field as the zero value
+func WithEpochTimeColumns(columns ...string) MappingOption {
+	return withEpochColumns(time.Second, columns)
+}
+
+// WithEpochMilliTimeColumns works like [WithEpochTimeColumns]
+// but the integers are Unix epoch milliseconds
+func WithEpochMilliTimeColumns(columns ...string) MappingOption {
+	return withEpochColumns(time.Millisecond, columns)
+}
+
+func withEpochColumns(unit time.Duration, columns []string) MappingOption {
+	return func(opt *mappingOptions) {
+		if opt.epochColumns == nil {
+			opt.epochColumns = make(map[string]time.Duration)
+		}
+		for _, column := range columns {
+			opt.epochColumns[column] = unit
+		}
+	}
+}
+
+// WithHstoreColumns scans the given PostgreSQL hstore columns as strings and parses them
+// with [ParseHstore] into map[string]string or map[string]*string fields.
+// NULL values in the hstore are empty strings in a map[string]string,
+// use map[string]*string to tell them apart. A NULL column leaves the field nil
+func WithHstoreColumns(columns ...string) MappingOption {
+	return func(opt *mappingOptions) {
+		if opt.hstoreColumns == nil {
+			opt.hstoreColumns = make(map[string]bool, len(columns))
+		}
+		for _, c := range columns {
+			opt.hstoreColumns[c] = true
+		}
+	}
+}
+
+// WithTextUnmarshalerColumns scans the given columns as strings and sets their fields
+// with [encoding.TextUnmarshaler], for types such as netip.Addr that do not implement [sql.Scanner].
+// If no columns are given, it is used for every field whose type implements encoding.TextUnmarshaler
+// except types that implement sql.Scanner and time types.
+// A NULL column leaves the field as the zero value, or nil for pointer fields.
+// Naming a column whose field does not implement encoding.TextUnmarshaler returns an error.
+//
+// Struct types with exported fields are mapped field by field, so they should also be
+// made scannable on the source with [WithScannableTypes]:
+//
+//	scan.WithScannableTypes((*sql.Scanner)(nil), (*encoding.TextUnmarshaler)(nil))
+func WithTextUnmarshalerColumns(columns ...string) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.textColumns.add(columns)
+	}
+}
+
+// WithBigNumberColumns scans the given columns, such as NUMERIC columns, as strings
+// and parses them into big.Int or big.Rat fields. If no columns are given, it is used for every
+// big.Int and big.Rat field. A big.Int field also accepts values with a decimal point or an exponent
+// as long as they are whole numbers, such as "12.00" from a NUMERIC(10, 2) column.
+// A NULL column leaves the field as the zero value, or nil for pointer fields.
+// Naming a column whose field is not a big.Int or big.Rat returns an error
+func WithBigNumberColumns(columns ...string) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.bigColumns.add(columns)
+	}
+}
+
+// isBigNumberType reports if the type is big.Int or big.Rat
+func isBigNumberType(typ reflect.Type) bool {
+	return typ == typeOf[big.Int]() || typ == typeOf[big.Rat]()
+}
+
+// parseBigNumber parses the text into dest which is a *big.Int or *big.Rat
+func parseBigNumber(dest any, text string) error {
+	switch dest := dest.(type) {
+	case *big.Int:
+		if _, ok := dest.SetString(text, 10); ok {
+			return nil
+		}
+
+		r, ok := new(big.Rat).SetString(text)
+		if !ok || !r.IsInt() {
+			return fmt.Errorf("cannot parse %q as an integer", text)
+		}
+
+		dest.Set(r.Num())
+		return nil
+
+	case *big.Rat:
+		if _, ok := dest.SetString(text); !ok {
+			return fmt.Errorf("cannot parse %q as a number", text)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("cannot parse a big number into %T", dest)
+}
+
+// fromEpoch converts an epoch in the given unit to a time in UTC
+func fromEpoch(epoch int64, unit time.Duration) time.Time {
+	perSecond := int64(time.Second / unit)
+	return time.Unix(epoch/perSecond, (epoch%perSecond)*int64(unit)).UTC()
+}
+
+// toEpoch converts a time to an epoch in the given unit
+func toEpoch(t time.Time, unit time.Duration) int64 {
+	perSecond := int64(time.Second / unit)
+	return t.Unix()*perSecond + int64(t.Nanosecond())/int64(unit)
+}
+
+// WithNullAsZero sets non-pointer fields to their zero value when the column is NULL
+// instead of returning the error from the driver.
+// To do this, every column is scanned into a pointer which is then dereferenced,
+// this means an extra allocation for each column of every row compared to
+// scanning directly into the field.
+// If used with [WithRowValidator], the values passed to the validator are pointers
+// to these intermediate pointers
+func WithNullAsZero() MappingOption {
+	return func(opt *mappingOptions) {
+		opt.nullAsZero = true
+	}
+}
+
+// WithNullTypeCoercion scans columns for fields of basic types through the matching
+// sql.Null* type, such as [sql.NullString] for string fields and [sql.NullTime] for
+// time.Time fields. The field is left as its zero value when the column is NULL.
+//
+// Integer fields narrower than the sql.Null* type, and unsigned integer fields,
+// return an error if the value does not fit in the field. Unsigned integers are
+// scanned through [sql.NullInt64], so values above math.MaxInt64 cannot be scanned.
+//
+// Unlike [WithNullAsZero], only fields of these types are affected, and other fields
+// are scanned directly. Pointer fields and types that implement [sql.Scanner] are not changed.
+// A [TypeConverter] takes precedence over this option
+func WithNullTypeCoercion() MappingOption {
+	return func(opt *mappingOptions) {
+		opt.nullCoercion = true
+	}
+}
+
+// WithTrimStringColumns trims trailing whitespace from the string fields of the given columns,
+// such as the padding of CHAR(n) columns. If no columns are given, all string fields are trimmed.
+// This also applies to *string fields and types defined from string.
+// Naming a column whose field is not a string returns an error
+func WithTrimStringColumns(columns ...string) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.trimColumns.add(columns)
+	}
+}
+
+// WithNilOnAllNull leaves the pointer struct fields at the given paths nil when all
+// the columns of their fields are NULL. This is useful for the nullable side of an
+// outer join. The paths are the Go field names separated by dots, e.g. "Post" or "Post.Author".
+//
+// Since the columns can be NULL, fields which are not pointers are scanned into pointers
+// in the same way as [WithNullAsZero] and are left as zero values when the column is NULL
+// but the struct is not nil
+func WithNilOnAllNull(fieldPaths ...string) MappingOption {
+	return func(opt *mappingOptions) {
+		opt.nilOnAllNull = append(opt.nilOnAllNull, fieldPaths...)
+	}
+}
+
+// WithContextFieldOverrides makes the mapper set the values from the map
+// in the context with [CtxKeyFieldOverrides] on the fields of every row.
+// The keys are the column names the fields map to, without any struct tag prefix.
+// Overrides always take precedence over the scanned values.
+// This is useful for things like redacting sensitive fields in middleware
+func WithContextFieldOverrides() MappingOption {
+	return func(opt *mappingOptions) {
+		opt.ctxOverrides = true
+	}
+}
+
+// WithArgField sets the field mapped to the given column name to the query
+// argument at argIndex on every row. The column does not need to be selected.
+// This is useful to carry an input value such as a tenant ID onto every row.
+//
+// The args are only available to mappers used with functions that run the query
+// such as [All] and [One]. With the FromRows functions, the mapper returns an error.
+// Values from [WithContextFieldOverrides] take precedence over arg fields
+func WithArgField(field string, argIndex int) MappingOption {
+	return func(opt *mappingOptions) {
+		if opt.argFields == nil {
+			opt.argFields = make(map[string]int)
+		}
+		opt.argFields[field] = argIndex
+	}
+}
+
 // WithMapperMods accepts mods used to modify the mapper
 func WithMapperMods(mods ...MapperMod) MappingOption {
 	return func(opt *mappingOptions) {
@@ -115,27 +1065,255 @@ func WithMapperMods(mods ...MapperMod) MappingOption {
 }
 
 func mapperFromMapping[T any](m mapping, typ reflect.Type, isPointer bool, opts mappingOptions) func(context.Context, cols) (func(*Row) (any, error), func(any) (T, error)) {
+	if opts.columnSeparator != "" {
+		m = m.withSeparator(opts.columnSeparator)
+	}
+
+	nilGroups, groupsErr := nilOnAllNullPositions(structType(typ), opts.nilOnAllNull)
+	aliasErr := checkAliases(opts.columnAliases)
+
+	remain, hasRemain := m.remainField()
+	var remainErr error
+	if hasRemain {
+		remainErr = checkMapField(typ, remain, "invalid remain field")
+	}
+
+	dynamic, dynamicErr := dynamicFields(typ, m, opts.dynamicColumns)
+
 	return func(ctx context.Context, c cols) (func(*Row) (any, error), func(any) (T, error)) {
+		if groupsErr != nil {
+			return ErrorMapper[T](groupsErr, "invalid nil on all null path")
+		}
+
+		if aliasErr != nil {
+			return ErrorMapper[T](aliasErr)
+		}
+
+		if remainErr != nil {
+			return ErrorMapper[T](remainErr)
+		}
+
+		if dynamicErr != nil {
+			return ErrorMapper[T](dynamicErr)
+		}
+
 		// Filter the mapping so we only ask for the available columns
-		filtered, err := filterColumns(ctx, c, m, opts.structTagPrefix)
+		filtered, err := filterColumns(ctx, c, m, opts)
 		if err != nil {
 			return ErrorMapper[T](err)
 		}
 
+		if opts.positionalFallback {
+			filtered = append(filtered, positionalFields(c, m, filtered, opts.structTagPrefix)...)
+		}
+
+		if matched, ok := ctx.Value(ctxKeyMatchedColumns).(*[]string); ok {
+			*matched = filtered.cols()
+		}
+
 		mapper := regular[T]{
 			typ:       typ,
 			isPointer: isPointer,
 			filtered:  filtered,
-			converter: opts.typeConverter,
 			validator: opts.rowValidator,
 		}
+
+		if len(nilGroups) > 0 {
+			mapper.nilGroups = len(nilGroups)
+			mapper.groupsOf = make([][]int, len(filtered))
+			for i, info := range filtered {
+				for g, pos := range nilGroups {
+					if len(info.position) > len(pos) && samePosition(info.position[:len(pos)], pos) {
+						mapper.groupsOf[i] = append(mapper.groupsOf[i], g)
+					}
+				}
+			}
+		}
+
+		mapper.strategies, err = fieldStrategies(typ, filtered, opts, mapper.nullable(opts.nullAsZero))
+		if err != nil {
+			return ErrorMapper[T](err)
+		}
+
+		unmatched := unmatchedColumns(c, filtered)
+
+		for i, d := range opts.dynamicColumns {
+			col := collector{info: dynamic[i]}
+			rest := make([]string, 0, len(unmatched))
+			for _, name := range unmatched {
+				if !strings.HasPrefix(name, d.prefix) {
+					rest = append(rest, name)
+					continue
+				}
+
+				col.cols = append(col.cols, name)
+				col.keys = append(col.keys, name[len(d.prefix):])
+			}
+
+			unmatched = rest
+			mapper.collectors = append(mapper.collectors, col)
+		}
+
+		if hasRemain {
+			mapper.collectors = append(mapper.collectors, collector{
+				info: remain,
+				cols: unmatched,
+				keys: unmatched,
+			})
+		}
+
+		var overrides []fieldOverride
+		if len(opts.argFields) > 0 {
+			argOverrides, err := getArgOverrides(ctx, typ, m, opts.argFields)
+			if err != nil {
+				return ErrorMapper[T](err)
+			}
+			overrides = append(overrides, argOverrides...)
+		}
+
+		if opts.ctxOverrides {
+			values, _ := ctx.Value(CtxKeyFieldOverrides).(map[string]any)
+			ctxOverrides, err := getOverrides(typ, m, values, "invalid override")
+			if err != nil {
+				return ErrorMapper[T](err)
+			}
+			overrides = append(overrides, ctxOverrides...)
+		}
+
+		var before func(*Row) (any, error)
+		var after func(any) (T, error)
+
 		switch {
-		case opts.typeConverter == nil && opts.rowValidator == nil:
-			return mapper.regular()
+		case mapper.isRegular():
+			before, after = mapper.regular()
 
 		default:
-			return mapper.allOptions()
+			before, after = mapper.allOptions()
+		}
+
+		if len(overrides) > 0 {
+			after = withOverrides(after, overrides)
+		}
+
+		return before, after
+	}
+}
+
+// nilOnAllNullPositions resolves the field paths to the positions of the fields
+func nilOnAllNullPositions(typ reflect.Type, paths []string) ([][]int, error) {
+	positions := make([][]int, 0, len(paths))
+	for _, path := range paths {
+		var position []int
+		current := typ
+
+		for _, name := range strings.Split(path, ".") {
+			if current.Kind() == reflect.Pointer {
+				current = current.Elem()
+			}
+
+			if current.Kind() != reflect.Struct {
+				return nil, fmt.Errorf("field path %q goes through %s which is not a struct", path, current)
+			}
+
+			field, ok := current.FieldByName(name)
+			if !ok {
+				return nil, fmt.Errorf("field path %q: no field %s in %s", path, name, current)
+			}
+
+			position = append(position, field.Index...)
+			current = field.Type
+		}
+
+		if current.Kind() != reflect.Pointer || current.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("field path %q must be a pointer to a struct, got %s", path, current)
+		}
+
+		positions = append(positions, position)
+	}
+
+	return positions, nil
+}
+
+type fieldOverride struct {
+	info mapinfo
+	val  reflect.Value
+}
+
+// getArgOverrides gets the query args from the context for the arg fields
+func getArgOverrides(ctx context.Context, typ reflect.Type, m mapping, fields map[string]int) ([]fieldOverride, error) {
+	args, _ := ctx.Value(ctxKeyQueryArgs).([]any)
+
+	values := make(map[string]any, len(fields))
+	for field, index := range fields {
+		if !m.has(field) {
+			err := fmt.Errorf("arg field %s does not match any field of %s", field, typ)
+			return nil, createError(err, "unknown arg field", field)
+		}
+
+		if index < 0 || index >= len(args) {
+			err := fmt.Errorf("arg index %d for field %s is out of range for %d args", index, field, len(args))
+			return nil, createError(err, "arg index out of range", field)
 		}
+
+		values[field] = args[index]
+	}
+
+	return getOverrides(typ, m, values, "invalid arg field")
+}
+
+// getOverrides checks that the values can be set on the fields
+// with the same column names
+func getOverrides(typ reflect.Type, m mapping, values map[string]any, meta string) ([]fieldOverride, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	overrides := make([]fieldOverride, 0, len(values))
+	for _, info := range m {
+		val, ok := values[info.name]
+		if !ok {
+			continue
+		}
+
+		ft := fieldOfType(typ, info.position).Type
+		rv := reflect.Zero(ft)
+		if val != nil {
+			rv = reflect.ValueOf(val)
+		}
+
+		if !rv.Type().AssignableTo(ft) {
+			err := fmt.Errorf("cannot use value of type %s for field %s of type %s", rv.Type(), info.name, ft)
+			return nil, createError(err, meta, info.name)
+		}
+
+		overrides = append(overrides, fieldOverride{info: info, val: rv})
+	}
+
+	return overrides, nil
+}
+
+func withOverrides[T any](after func(any) (T, error), overrides []fieldOverride) func(any) (T, error) {
+	return func(link any) (T, error) {
+		t, err := after(link)
+		if err != nil {
+			return t, err
+		}
+
+		row := reflect.ValueOf(&t).Elem()
+		if row.Kind() == reflect.Pointer {
+			if row.IsNil() {
+				return t, nil
+			}
+			row = row.Elem()
+		}
+
+		for _, o := range overrides {
+			initPointers(row, o.info.init)
+
+			fieldByIndex(row, o.info.position).Set(o.val)
+		}
+
+		return t, nil
 	}
 }
 
@@ -143,12 +1321,138 @@ type regular[T any] struct {
 	isPointer bool
 	typ       reflect.Type
 	filtered  mapping
-	converter TypeConverter
 	validator RowValidator
+
+	// the strategy of each filtered field, nil to scan directly into the field
+	strategies []*fieldStrategy
+
+	// the map fields that collect the columns not mapped to other fields
+	collectors []collector
+
+	// the number of fields set with WithNilOnAllNull and
+	// the indexes of the fields each column belongs to
+	nilGroups int
+	groupsOf  [][]int
+}
+
+// collector holds the columns that are collected into a map field
+// and the keys they are stored under
+type collector struct {
+	info mapinfo
+	cols []string
+	keys []string
+}
+
+// collectedColumns returns the number of columns scanned for the collectors
+func (s regular[T]) collectedColumns() int {
+	var n int
+	for _, col := range s.collectors {
+		n += len(col.cols)
+	}
+
+	return n
+}
+
+// nullable returns a function that reports if the column can be NULL
+// without scanning into a pointer field
+func (s regular[T]) nullable(nullAsZero bool) func(i int) bool {
+	return func(i int) bool {
+		return nullAsZero || (s.groupsOf != nil && len(s.groupsOf[i]) > 0)
+	}
+}
+
+// isRegular reports if every field is scanned directly and
+// the values do not need to be checked after scanning
+func (s regular[T]) isRegular() bool {
+	if s.validator != nil || s.nilGroups > 0 || len(s.collectors) > 0 {
+		return false
+	}
+
+	for _, strategy := range s.strategies {
+		if strategy != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// allNullGroups reports for each field set with WithNilOnAllNull
+// if all its scanned columns are NULL
+func (s regular[T]) allNullGroups(vals []reflect.Value) []bool {
+	if s.nilGroups == 0 {
+		return nil
+	}
+
+	allNull := make([]bool, s.nilGroups)
+	for g := range allNull {
+		allNull[g] = true
+	}
+
+	for i, groups := range s.groupsOf {
+		if isNullDest(vals[i]) {
+			continue
+		}
+
+		for _, g := range groups {
+			allNull[g] = false
+		}
+	}
+
+	return allNull
+}
+
+// isNullDest reports if the value scanned into the destination was NULL.
+// Destinations that implement [driver.Valuer], such as the sql.Null* types,
+// are NULL if their value is nil
+func isNullDest(dest reflect.Value) bool {
+	if valuer, ok := dest.Interface().(driver.Valuer); ok {
+		val, err := valuer.Value()
+		return err == nil && val == nil
+	}
+
+	val := dest.Elem()
+	switch val.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		return val.IsNil()
+	}
+
+	return false
+}
+
+// unmatchedColumns returns the columns that are not in the filtered mapping
+func unmatchedColumns(c cols, filtered mapping) []string {
+	var unknown []string
+	for i, name := range c {
+		var found bool
+		for _, info := range filtered {
+			if info.positional && info.index == i || !info.positional && info.name == name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+
+	return unknown
+}
+
+// scheduleField schedules the scan of the column of the field into dest
+func scheduleField(v *Row, info mapinfo, dest reflect.Value) {
+	if info.positional {
+		v.ScheduleScanByIndex(info.index, dest.Interface())
+		return
+	}
+
+	v.ScheduleScanx(info.name, dest)
 }
 
 func (s regular[T]) regular() (func(*Row) (any, error), func(any) (T, error)) {
 	return func(v *Row) (any, error) {
+
 			var row reflect.Value
 			if s.isPointer {
 				row = reflect.New(s.typ.Elem()).Elem()
@@ -157,17 +1461,10 @@ func (s regular[T]) regular() (func(*Row) (any, error), func(any) (T, error)) {
 			}
 
 			for _, info := range s.filtered {
-				for _, v := range info.init {
-					pv := row.FieldByIndex(v)
-					if !pv.IsZero() {
-						continue
-					}
+				initPointers(row, info.init)
 
-					pv.Set(reflect.New(pv.Type().Elem()))
-				}
-
-				fv := row.FieldByIndex(info.position)
-				v.ScheduleScanx(info.name, fv.Addr())
+				fv := fieldByIndex(row, info.position)
+				scheduleField(v, info, fv.Addr())
 			}
 
 			return row, nil
@@ -184,28 +1481,38 @@ func (s regular[T]) regular() (func(*Row) (any, error), func(any) (T, error)) {
 
 func (s regular[T]) allOptions() (func(*Row) (any, error), func(any) (T, error)) {
 	return func(v *Row) (any, error) {
-			row := make([]reflect.Value, len(s.filtered))
+			row := make([]reflect.Value, len(s.filtered), len(s.filtered)+s.collectedColumns())
 
 			for i, info := range s.filtered {
-				var ft reflect.Type
-				if s.isPointer {
-					ft = s.typ.Elem().FieldByIndex(info.position).Type
+				if strategy := s.strategies[i]; strategy != nil {
+					row[i] = strategy.dest()
 				} else {
-					ft = s.typ.FieldByIndex(info.position).Type
+					row[i] = reflect.New(fieldOfType(s.typ, info.position).Type)
 				}
 
-				if s.converter != nil {
-					row[i] = s.converter.TypeToDestination(ft)
-				} else {
-					row[i] = reflect.New(ft)
+				scheduleField(v, info, row[i])
+			}
+
+			// The columns for the map fields are after the other fields
+			for _, col := range s.collectors {
+				elem := fieldOfType(s.typ, col.info.position).Type
+				if col.info.isPointer {
+					elem = elem.Elem()
 				}
+				elem = elem.Elem()
 
-				v.ScheduleScanx(info.name, row[i])
+				for _, name := range col.cols {
+					dest := reflect.New(elem)
+					v.ScheduleScanx(name, dest)
+					row = append(row, dest)
+				}
 			}
 
 			return row, nil
 		}, func(v any) (T, error) {
 			vals := v.([]reflect.Value)
+			collected := vals[len(s.filtered):]
+			vals = vals[:len(s.filtered)]
 
 			if s.validator != nil && !s.validator(s.filtered.cols(), vals) {
 				var t T
@@ -219,29 +1526,44 @@ func (s regular[T]) allOptions() (func(*Row) (any, error), func(any) (T, error))
 				row = reflect.New(s.typ).Elem()
 			}
 
+			allNull := s.allNullGroups(vals)
+
+		fields:
 			for i, info := range s.filtered {
-				for _, v := range info.init {
-					pv := row.FieldByIndex(v)
-					if !pv.IsZero() {
-						continue
+				if s.groupsOf != nil {
+					for _, g := range s.groupsOf[i] {
+						if allNull[g] {
+							continue fields
+						}
 					}
+				}
 
-					pv.Set(reflect.New(pv.Type().Elem()))
+				strategy := s.strategies[i]
+				if strategy == nil {
+					initField(row, info).Set(vals[i].Elem())
+					continue
 				}
 
-				var val reflect.Value
-				if s.converter != nil {
-					val = s.converter.ValueFromDestination(vals[i])
-				} else {
-					val = vals[i].Elem()
+				if err := strategy.set(row, vals[i]); err != nil {
+					var t T
+					return t, err
 				}
+			}
 
-				fv := row.FieldByIndex(info.position)
-				if info.isPointer {
-					fv.Elem().Set(val)
-				} else {
-					fv.Set(val)
+			for _, col := range s.collectors {
+				initPointers(row, col.info.init)
+
+				fv := fieldByIndex(row, col.info.position)
+				if col.info.isPointer {
+					fv = fv.Elem()
+				}
+
+				collectedMap := reflect.MakeMapWithSize(fv.Type(), len(col.cols))
+				for i, key := range col.keys {
+					collectedMap.SetMapIndex(reflect.ValueOf(key).Convert(fv.Type().Key()), collected[i].Elem())
 				}
+				fv.Set(collectedMap)
+				collected = collected[len(col.cols):]
 			}
 
 			if s.isPointer {
@@ -2,9 +2,14 @@ package scan
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"math/big"
+	"net/netip"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -60,7 +65,7 @@ func RunMapperTest[T any](t *testing.T, name string, tc MapperTest[T]) {
 		if diff := diffErr(tc.ExpectedAfterError, err); diff != "" {
 			t.Fatalf("diff: %s", diff)
 		}
-		if diff := cmp.Diff(tc.ExpectedVal, val); diff != "" {
+		if diff := cmp.Diff(tc.ExpectedVal, val, valueComparers); diff != "" {
 			t.Fatalf("diff: %s", diff)
 		}
 	}
@@ -189,6 +194,24 @@ func TestMapMapper(t *testing.T) {
 		Mapper:      MapMapper[any],
 		ExpectedVal: mapToVals[any](goodSlice),
 	})
+	RunMapperTest(t, "MapMapperStrict", MapperTest[map[string]any]{
+		row: &Row{
+			columns: columns(len(goodSlice)),
+		},
+		scanned:     goodSlice,
+		Mapper:      MapMapperStrict[any],
+		ExpectedVal: mapToVals[any](goodSlice),
+	})
+
+	RunMapperTest(t, "MapMapperStrict duplicate", MapperTest[map[string]any]{
+		row: &Row{
+			columns: columnNames("id", "id"),
+		},
+		scanned:             []any{1, 2},
+		Mapper:              MapMapperStrict[any],
+		ExpectedBeforeError: createError(nil, "duplicate column", "id"),
+		ExpectedAfterError:  createError(nil, "duplicate column", "id"),
+	})
 }
 
 func TestStructMapper(t *testing.T) {
@@ -270,6 +293,20 @@ func TestStructMapper(t *testing.T) {
 		ExpectedVal: Tagged{ID: 1, Name: "The Name", Email: "user@example.com"},
 	})
 
+	RunMapperTest(t, "slice fields", MapperTest[SliceUser]{
+		row: &Row{
+			columns: columnNames("id", "tags", "data", "blogs"),
+		},
+		scanned: []any{1, []string{"a", "b"}, []byte("raw"), []Blog{{ID: 2}}},
+		Mapper:  StructMapper[SliceUser](),
+		ExpectedVal: SliceUser{
+			ID:    1,
+			Tags:  []string{"a", "b"},
+			Data:  []byte("raw"),
+			Blogs: []Blog{{ID: 2}},
+		},
+	})
+
 	RunCustomStructMapperTest(t, "custom column separator", CustomStructMapperTest[Blog]{
 		MapperTest: MapperTest[Blog]{
 			row: &Row{
@@ -287,6 +324,67 @@ func TestStructMapper(t *testing.T) {
 		Options: []MappingSourceOption{WithColumnSeparator(",")},
 	})
 
+	RunMapperTest(t, "column separator override", MapperTest[Blog]{
+		row: &Row{
+			columns: columnNames("id", "user__id", "user__name", "user__created_at"),
+		},
+		scanned: []any{100, 10, "The Name", now},
+		Mapper:  StructMapper[Blog](WithColumnSeparatorOverride("__")),
+		ExpectedVal: Blog{
+			ID: 100,
+			User: UserWithTimestamps{
+				User:       User{ID: 10, Name: "The Name"},
+				Timestamps: &Timestamps{CreatedAt: now},
+			},
+		},
+	})
+
+	deepBlog := Blog{
+		ID: 100,
+		User: UserWithTimestamps{
+			User: User{ID: 10},
+			Blog: &Blog{ID: 2, User: UserWithTimestamps{User: User{Name: "The Name"}}},
+		},
+	}
+
+	RunCustomStructMapperTest(t, "multi-character column separator", CustomStructMapperTest[Blog]{
+		MapperTest: MapperTest[Blog]{
+			row: &Row{
+				columns: columnNames("id", "user__id", "user__blog__id", "user__blog__user__name"),
+			},
+			scanned:     []any{100, 10, 2, "The Name"},
+			ExpectedVal: deepBlog,
+		},
+		Options: []MappingSourceOption{WithColumnSeparator("__")},
+	})
+
+	RunMapperTest(t, "multi-character column separator override", MapperTest[Blog]{
+		row: &Row{
+			columns: columnNames("id", "user__id", "user__blog__id", "user__blog__user__name"),
+		},
+		scanned:     []any{100, 10, 2, "The Name"},
+		Mapper:      StructMapper[Blog](WithColumnSeparatorOverride("__")),
+		ExpectedVal: deepBlog,
+	})
+
+	RunMapperTest(t, "multi-character column separator with prefix", MapperTest[Blog]{
+		row: &Row{
+			columns: columnNames("blog__id", "blog__user__id", "blog__user__blog__id", "blog__user__blog__user__name"),
+		},
+		scanned:     []any{100, 10, 2, "The Name"},
+		Mapper:      StructMapper[Blog](WithColumnSeparatorOverride("__"), WithStructTagPrefix("blog__")),
+		ExpectedVal: deepBlog,
+	})
+
+	RunMapperTest(t, "multi-character column separator with embedded prefix", MapperTest[OrderedUser]{
+		row: &Row{
+			columns: columnNames("id", "owner__id", "owner__name", "pet_id"),
+		},
+		scanned:     []any{1, 2, "The Owner", 3},
+		Mapper:      StructMapper[OrderedUser](WithColumnSeparatorOverride("__")),
+		ExpectedVal: OrderedUser{ID: 1, Owner: &Owner{ID: 2, Name: "The Owner"}, Pet: Pet{ID: 3}},
+	})
+
 	RunCustomStructMapperTest(t, "custom name mapper", CustomStructMapperTest[Blog]{
 		MapperTest: MapperTest[Blog]{
 			row: &Row{
@@ -304,6 +402,17 @@ func TestStructMapper(t *testing.T) {
 		Options: []MappingSourceOption{WithFieldNameMapper(strings.ToUpper)},
 	})
 
+	RunCustomStructMapperTest(t, "custom name mapper with tags", CustomStructMapperTest[PartlyTagged]{
+		MapperTest: MapperTest[PartlyTagged]{
+			row: &Row{
+				columns: columnNames("user_id", "FirstName", "LastName"),
+			},
+			scanned:     []any{1, "John", "Doe"},
+			ExpectedVal: PartlyTagged{ID: 1, FirstName: "John", LastName: "Doe"},
+		},
+		Options: []MappingSourceOption{WithFieldNameMapper(func(s string) string { return s })},
+	})
+
 	RunCustomStructMapperTest(t, "custom tag", CustomStructMapperTest[Tagged]{
 		MapperTest: MapperTest[Tagged]{
 			row: &Row{
@@ -333,6 +442,76 @@ func TestStructMapper(t *testing.T) {
 		ExpectedVal: User{ID: 0, Name: "The Name"},
 	})
 
+	RunMapperTest(t, "with prefix fallthrough", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "prefix--name"),
+		},
+		scanned:     []any{1, "The Name"},
+		Mapper:      StructMapper[User](WithStructTagPrefix("prefix--"), WithPrefixFallthrough()),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "with prefix fallthrough and both columns", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "name", "prefix--name"),
+		},
+		scanned:     []any{1, "Bare Name", "The Name"},
+		Mapper:      StructMapper[User](WithStructTagPrefix("prefix--"), WithPrefixFallthrough()),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "with column aliases", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("prefix--id", "usr_nm"),
+		},
+		scanned: []any{1, "The Name"},
+		Mapper: StructMapper[User](
+			WithStructTagPrefix("prefix--"),
+			WithColumnAliases(map[string]string{"usr_nm": "name"}),
+		),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "with conflicting column aliases", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "usr_nm", "user_name"),
+		},
+		scanned:             []any{1, "The Name", "The Name"},
+		Mapper:              StructMapper[User](WithColumnAliases(map[string]string{"usr_nm": "name", "user_name": "name"})),
+		ExpectedBeforeError: createError(nil, "duplicate column alias", "name"),
+		ExpectedAfterError:  createError(nil, "duplicate column alias", "name"),
+	})
+
+	RunMapperTest(t, "with column aliases from the context", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "nombre"),
+		},
+		Context:     map[contextKey]any{CtxKeyColumnAliases: map[string]string{"nombre": "name"}},
+		scanned:     []any{1, "The Name"},
+		Mapper:      StructMapper[User](WithColumnAliases(map[string]string{"nombre": "id"})),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "without column aliases in the context", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "nombre"),
+		},
+		scanned:     []any{1, "The Name"},
+		Mapper:      StructMapper[User](),
+		ExpectedVal: User{ID: 1},
+	})
+
+	RunMapperTest(t, "with conflicting column aliases from the context", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "nombre"),
+		},
+		Context:             map[contextKey]any{CtxKeyColumnAliases: map[string]string{"nombre": "name"}},
+		scanned:             []any{1, "The Name"},
+		Mapper:              StructMapper[User](WithColumnAliases(map[string]string{"usr_nm": "name"})),
+		ExpectedBeforeError: createError(nil, "duplicate column alias", "name"),
+		ExpectedAfterError:  createError(nil, "duplicate column alias", "name"),
+	})
+
 	RunMapperTest(t, "with type converter", MapperTest[User]{
 		row: &Row{
 			columns: columnNames("id", "name"),
@@ -342,6 +521,15 @@ func TestStructMapper(t *testing.T) {
 		ExpectedVal: User{ID: 1, Name: "The Name"},
 	})
 
+	RunMapperTest(t, "with column type converter", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned:     []any{1, wrapper{toPtr("The Name")}},
+		Mapper:      StructMapper[User](WithTypeConverter(columnTypeConverter{})),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
 	RunMapperTest(t, "with type converter ptr", MapperTest[*User]{
 		row: &Row{
 			columns: columnNames("id", "name"),
@@ -402,6 +590,72 @@ func TestStructMapper(t *testing.T) {
 		ExpectedVal: User{ID: 0, Name: ""},
 	})
 
+	RunMapperTest(t, "with interface factory", MapperTest[NamedUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned: []any{1, StringName("The Name")},
+		Mapper: StructMapper[NamedUser](WithInterfaceFactory(map[reflect.Type]func() reflect.Value{
+			typeOf[Named](): func() reflect.Value {
+				return reflect.New(typeOf[StringName]())
+			},
+		})),
+		ExpectedVal: NamedUser{ID: 1, Name: toPtr(StringName("The Name"))},
+	})
+
+	RunMapperTest(t, "with field builder", MapperTest[CounterUser]{
+		row: &Row{
+			columns: columnNames("id", "visits"),
+		},
+		scanned: []any{1, int64(5)},
+		Mapper: StructMapper[CounterUser](WithFieldBuilder("visits", func(current, scanned any) any {
+			return current.(Counter).Add(int(scanned.(int64)))
+		})),
+		ExpectedVal: CounterUser{ID: 1, Visits: 5},
+	})
+
+	RunMapperTest(t, "with null as zero", MapperTest[PtrUser1]{
+		row: &Row{
+			columns: columnNames("id", "name", "created_at"),
+		},
+		scanned:     []any{toPtr(1), (*string)(nil), &now},
+		Mapper:      StructMapper[PtrUser1](WithNullAsZero()),
+		ExpectedVal: PtrUser1{ID: toPtr(1), PtrTimestamps: PtrTimestamps{CreatedAt: &now}},
+	})
+
+	RunMapperTest(t, "with row validator and pointer fields", MapperTest[PtrUser1]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned: []any{toPtr(1), "The Name"},
+		Mapper: StructMapper[PtrUser1](WithRowValidator(func([]string, []reflect.Value) bool {
+			return true
+		})),
+		ExpectedVal: PtrUser1{ID: toPtr(1), Name: "The Name"},
+	})
+
+	RunMapperTest(t, "with row validator map pass", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned: []any{1, "The Name"},
+		Mapper: StructMapper[User](WithRowValidatorMap(func(vals map[string]reflect.Value) bool {
+			return vals["id"].Elem().Int() == 1 && vals["name"].Elem().String() == "The Name"
+		})),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "with row validator map fail", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned: []any{2, "The Name"},
+		Mapper: StructMapper[User](WithRowValidatorMap(func(vals map[string]reflect.Value) bool {
+			return vals["id"].Elem().Int() == 1
+		})),
+		ExpectedVal: User{},
+	})
+
 	RunMapperTest(t, "with mod", MapperTest[*User]{
 		row: &Row{
 			columns: columnNames("id", "name"),
@@ -446,29 +700,1554 @@ func TestScannable(t *testing.T) {
 	}
 }
 
-func TestScannableErrors(t *testing.T) {
-	cases := map[string]struct {
-		typ any
-		err error
-	}{
-		"nil": {
-			typ: nil,
-			err: fmt.Errorf("scannable type must be a pointer, got <nil>"),
+func TestScannableMatrix(t *testing.T) {
+	m, err := defaultStructMapper.getMapping(reflect.TypeOf(GeoMatrix{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapping: %v", err)
+	}
+
+	expected := mapping{
+		{name: "value_value", path: []string{"value_value"}, position: []int{0}},
+		{name: "pointer_value", path: []string{"pointer_value"}, position: []int{1}, init: [][]int{{1}}, isPointer: true},
+		{name: "value_pointer", path: []string{"value_pointer"}, position: []int{2}},
+		{name: "pointer_pointer", path: []string{"pointer_pointer"}, position: []int{3}, init: [][]int{{3}}, isPointer: true},
+	}
+
+	if diff := cmp.Diff(expected, m, cmp.AllowUnexported(mapinfo{})); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	RunMapperTest(t, "scan", MapperTest[GeoMatrix]{
+		row: &Row{
+			columns: columnNames("value_value", "pointer_value", "value_pointer", "pointer_pointer"),
 		},
-		"non-pointer": {
-			typ: User{},
-			err: fmt.Errorf("scannable type must be a pointer, got struct: scan.User"),
+		scanned: []any{
+			GeoValue{Lat: 1, Lng: 2},
+			&GeoValue{Lat: 3, Lng: 4},
+			GeoPointer{Lat: 5, Lng: 6},
+			&GeoPointer{Lat: 7, Lng: 8},
 		},
-		"non-interface": {
-			typ: &User{},
-			err: fmt.Errorf("scannable type must be a pointer to an interface, got struct: scan.User"),
+		Mapper: StructMapper[GeoMatrix](),
+		ExpectedVal: GeoMatrix{
+			ValueValue:     GeoValue{Lat: 1, Lng: 2},
+			PointerValue:   &GeoValue{Lat: 3, Lng: 4},
+			ValuePointer:   GeoPointer{Lat: 5, Lng: 6},
+			PointerPointer: &GeoPointer{Lat: 7, Lng: 8},
+		},
+	})
+
+	RunMapperTest(t, "partial scan", MapperTest[GeoMatrix]{
+		row: &Row{
+			columns: columnNames("value_pointer"),
 		},
+		scanned:     []any{GeoPointer{Lat: 5, Lng: 6}},
+		Mapper:      StructMapper[GeoMatrix](),
+		ExpectedVal: GeoMatrix{ValuePointer: GeoPointer{Lat: 5, Lng: 6}},
+	})
+}
+
+func TestScannableConcreteTypes(t *testing.T) {
+	type Money struct {
+		Units int
+		Cents int
 	}
 
-	for name, test := range cases {
-		t.Run(name, func(t *testing.T) {
-			_, err := NewStructMapperSource(WithScannableTypes(test.typ))
-			if diff := diffErr(test.err, err); diff != "" {
+	type Product struct {
+		ID    int
+		Price Money
+		Sale  *Money
+	}
+
+	m, err := defaultStructMapper.getMapping(reflect.TypeOf(Product{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapping: %v", err)
+	}
+
+	expected := []string{"id", "price.units", "price.cents", "sale.units", "sale.cents"}
+	if diff := cmp.Diff(expected, m.cols()); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	src, err := NewStructMapperSource(WithScannableConcreteTypes(Money{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+
+	m, err = src.getMapping(reflect.TypeOf(Product{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapping: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"id", "price", "sale"}, m.cols()); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	for _, typ := range []any{nil, (*sql.Scanner)(nil)} {
+		if _, err := NewStructMapperSource(WithScannableConcreteTypes(typ)); err == nil {
+			t.Fatalf("expected an error for %T", typ)
+		}
+	}
+}
+
+func TestScannableFallback(t *testing.T) {
+	RunCustomStructMapperTest(t, "whole", CustomStructMapperTest[PlaceUser]{
+		MapperTest: MapperTest[PlaceUser]{
+			row: &Row{
+				columns: columnNames("id", "location"),
+			},
+			scanned:     []any{1, GeoPointer{Lat: 1, Lng: 2}},
+			ExpectedVal: PlaceUser{ID: 1, Location: GeoPointer{Lat: 1, Lng: 2}},
+		},
+		Options: []MappingSourceOption{WithScannableFallback()},
+	})
+
+	RunCustomStructMapperTest(t, "fields", CustomStructMapperTest[PlaceUser]{
+		MapperTest: MapperTest[PlaceUser]{
+			row: &Row{
+				columns: columnNames("id", "location.lat", "location.lng"),
+			},
+			scanned:     []any{1, 1.0, 2.0},
+			ExpectedVal: PlaceUser{ID: 1, Location: GeoPointer{Lat: 1, Lng: 2}},
+		},
+		Options: []MappingSourceOption{WithScannableFallback()},
+	})
+
+	src, err := NewStructMapperSource(WithScannableFallback())
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+
+	m, err := src.getMapping(reflect.TypeOf(PlaceUser{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapping: %v", err)
+	}
+
+	filtered, err := filterColumns(context.Background(), []string{"location.lat", "id", "location"}, m, mappingOptions{})
+	if err != nil {
+		t.Fatalf("couldn't filter columns: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"id", "location"}, filtered.cols()); diff != "" {
+		t.Fatalf("whole column should take precedence: %s", diff)
+	}
+
+	m, err = defaultStructMapper.getMapping(reflect.TypeOf(PlaceUser{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapping: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"id", "location"}, m.cols()); diff != "" {
+		t.Fatalf("fields should not be mapped without the option: %s", diff)
+	}
+}
+
+func TestFieldNameMapperPath(t *testing.T) {
+	type Author struct {
+		FullName string
+	}
+
+	type Post struct {
+		PostTitle string
+		Author    Author
+		Timestamps
+	}
+
+	var paths [][]string
+	src, err := NewStructMapperSource(WithFieldNameMapperPath(func(path []string) string {
+		paths = append(paths, path)
+		if len(path) == 1 {
+			return strings.ToLower(path[0][:1])
+		}
+		return SnakeCase(path[len(path)-1])
+	}))
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+
+	m, err := src.getMapping(reflect.TypeOf(Post{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapping: %v", err)
+	}
+
+	expected := []string{"p", "a.full_name", "created_at", "updated_at"}
+	if diff := cmp.Diff(expected, m.cols()); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	expectedPaths := [][]string{
+		{"PostTitle"},
+		{"Author"},
+		{"Author", "FullName"},
+		{"Timestamps", "CreatedAt"},
+		{"Timestamps", "UpdatedAt"},
+	}
+	if diff := cmp.Diff(expectedPaths, paths); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+func TestEmbeddedPrefix(t *testing.T) {
+	RunMapperTest(t, "prefixed embedded structs", MapperTest[OwnerPet]{
+		row: &Row{
+			columns: columnNames("owner_id", "owner_name", "pet_id"),
+		},
+		scanned:     []any{1, "The Name", 2},
+		Mapper:      StructMapper[OwnerPet](),
+		ExpectedVal: OwnerPet{Owner: Owner{ID: 1, Name: "The Name"}, Pet: Pet{ID: 2}},
+	})
+
+	m, err := defaultStructMapper.getMapping(reflect.TypeOf(OwnerPet{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapping: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"owner_id", "owner_name", "pet_id"}, m.cols()); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	m = m.withSeparator(".")
+	if diff := cmp.Diff([]string{"owner_id", "owner_name", "pet_id"}, m.cols()); diff != "" {
+		t.Fatalf("prefix should be kept with a separator override: %s", diff)
+	}
+}
+
+func TestStrictMapping(t *testing.T) {
+	RunMapperTest(t, "not strict", MapperTest[DuplicateUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned:     []any{1, "The Name"},
+		Mapper:      StructMapper[DuplicateUser](),
+		ExpectedVal: DuplicateUser{ID: 1, Name: "The Name"},
+	})
+
+	src, err := NewStructMapperSource(WithStrictMapping())
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+
+	RunMapperTest(t, "strict", MapperTest[DuplicateUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned:             []any{1, "The Name"},
+		Mapper:              CustomStructMapper[DuplicateUser](src),
+		ExpectedBeforeError: createError(nil, "duplicate column", "name"),
+		ExpectedAfterError:  createError(nil, "duplicate column", "name"),
+	})
+
+	RunMapperTest(t, "strict without duplicates", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned:     []any{1, "The Name"},
+		Mapper:      CustomStructMapper[User](src),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+}
+
+func TestColumnTransformer(t *testing.T) {
+	normalize := func(name string) string {
+		return strings.ToLower(strings.Trim(name, `"`))
+	}
+
+	RunCustomStructMapperTest(t, "quoted and uppercase", CustomStructMapperTest[User]{
+		MapperTest: MapperTest[User]{
+			row: &Row{
+				columns: columnNames(`"ID"`, `"Name"`),
+			},
+			scanned:     []any{1, "The Name"},
+			ExpectedVal: User{ID: 1, Name: "The Name"},
+		},
+		Options: []MappingSourceOption{WithColumnTransformer(normalize)},
+	})
+
+	m, err := defaultStructMapper.getMapping(reflect.TypeOf(User{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapping: %v", err)
+	}
+
+	filtered, err := filterColumns(context.Background(), []string{`"User.ID"`, `"name"`}, m, mappingOptions{structTagPrefix: "user.", columnTransformer: normalize})
+	if err != nil {
+		t.Fatalf("couldn't filter columns: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{`"User.ID"`}, filtered.cols()); diff != "" {
+		t.Fatalf("prefix should be matched after transforming: %s", diff)
+	}
+}
+
+func TestSourceCache(t *testing.T) {
+	src, err := NewStructMapperSource(WithCacheSize(2))
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+	impl := src.(*mapperSourceImpl)
+
+	for _, typ := range []reflect.Type{typeOf[User](), typeOf[PtrUser1](), typeOf[User](), typeOf[Timestamps]()} {
+		if _, err := impl.getMapping(typ); err != nil {
+			t.Fatalf("couldn't get mapping: %v", err)
+		}
+	}
+
+	if len(impl.cache) != 2 {
+		t.Fatalf("expected 2 cached mappings, got %d", len(impl.cache))
+	}
+
+	if _, ok := impl.cache[typeOf[PtrUser1]()]; ok {
+		t.Fatal("least recently used mapping should be evicted")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, typ := range []reflect.Type{typeOf[User](), typeOf[PtrUser1](), typeOf[Timestamps]()} {
+				if _, err := impl.getMapping(typ); err != nil {
+					t.Errorf("couldn't get mapping: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	src.ClearCache()
+	if len(impl.cache) != 0 {
+		t.Fatalf("expected an empty cache, got %d", len(impl.cache))
+	}
+
+	if _, err := NewStructMapperSource(WithCacheSize(-1)); err == nil {
+		t.Fatal("expected an error for a negative cache size")
+	}
+}
+
+func TestScannableErrors(t *testing.T) {
+	cases := map[string]struct {
+		typ any
+		err error
+	}{
+		"nil": {
+			typ: nil,
+			err: fmt.Errorf("scannable type must be a pointer, got <nil>"),
+		},
+		"non-pointer": {
+			typ: User{},
+			err: fmt.Errorf("scannable type must be a pointer, got struct: scan.User"),
+		},
+		"non-interface": {
+			typ: &User{},
+			err: fmt.Errorf("scannable type must be a pointer to an interface, got struct: scan.User"),
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewStructMapperSource(WithScannableTypes(test.typ))
+			if diff := diffErr(test.err, err); diff != "" {
+				t.Fatalf("diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	// Setting a string into a Counter field panics in reflect
+	badBuilder := WithFieldBuilder("visits", func(current, scanned any) any {
+		return "not a counter"
+	})
+
+	RunMapperTest(t, "after", MapperTest[CounterUser]{
+		row: &Row{
+			columns: columnNames("id", "visits"),
+		},
+		scanned:            []any{1, int64(5)},
+		Mapper:             StructMapper[CounterUser](badBuilder, WithPanicRecovery()),
+		ExpectedAfterError: createError(nil, "panic"),
+	})
+
+	badMod := func(ctx context.Context, c cols) (BeforeFunc, AfterMod) {
+		panic("bad mod")
+	}
+
+	RunMapperTest(t, "creation", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned:             []any{1, "The Name"},
+		Mapper:              StructMapper[User](WithMapperMods(badMod), WithPanicRecovery()),
+		ExpectedBeforeError: createError(nil, "panic"),
+		ExpectedAfterError:  createError(nil, "panic"),
+	})
+
+	t.Run("not recovered by default", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("expected a panic")
+			}
+		}()
+
+		before, after := StructMapper[CounterUser](badBuilder)(context.Background(), columnNames("id", "visits"))
+		row := &Row{columns: columnNames("id", "visits"), scanDestinations: make([]reflect.Value, 2)}
+
+		link, err := before(row)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, _ = after(link)
+	})
+}
+
+func TestJSONColumns(t *testing.T) {
+	RunMapperTest(t, "valid", MapperTest[JSONUser]{
+		row: &Row{
+			columns: columnNames("id", "metadata", "settings"),
+		},
+		scanned: []any{1, []byte(`{"key":"value"}`), []byte(`{"theme":"dark"}`)},
+		Mapper:  StructMapper[JSONUser](WithJSONColumns("metadata")),
+		ExpectedVal: JSONUser{
+			ID:       1,
+			Metadata: map[string]any{"key": "value"},
+			Settings: &JSONSettings{Theme: "dark"},
+		},
+	})
+
+	RunMapperTest(t, "null", MapperTest[JSONUser]{
+		row: &Row{
+			columns: columnNames("id", "metadata", "settings"),
+		},
+		scanned:     []any{1, []byte(nil), []byte(nil)},
+		Mapper:      StructMapper[JSONUser](WithJSONColumns("metadata")),
+		ExpectedVal: JSONUser{ID: 1},
+	})
+
+	RunMapperTest(t, "invalid", MapperTest[JSONUser]{
+		row: &Row{
+			columns: columnNames("id", "metadata", "settings"),
+		},
+		scanned:            []any{1, []byte(`{"key":`), []byte(nil)},
+		Mapper:             StructMapper[JSONUser](WithJSONColumns("metadata")),
+		ExpectedAfterError: createError(nil, "invalid json", "metadata"),
+	})
+}
+
+func TestTimeLayout(t *testing.T) {
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	mapper := StructMapper[TimeStringUser](
+		WithTimeLayout("created_at", time.RFC3339),
+		WithTimeLayout("updated_at", "2006-01-02 15:04:05"),
+	)
+
+	RunMapperTest(t, "valid", MapperTest[TimeStringUser]{
+		row: &Row{
+			columns: columnNames("id", "created_at", "updated_at"),
+		},
+		scanned: []any{
+			1,
+			sql.NullString{String: "2023-01-02T03:04:05Z", Valid: true},
+			sql.NullString{String: "2023-01-02 03:04:05", Valid: true},
+		},
+		Mapper:      mapper,
+		ExpectedVal: TimeStringUser{ID: 1, CreatedAt: created, UpdatedAt: &created},
+	})
+
+	RunMapperTest(t, "null", MapperTest[TimeStringUser]{
+		row: &Row{
+			columns: columnNames("id", "created_at", "updated_at"),
+		},
+		scanned:     []any{1, sql.NullString{}, sql.NullString{}},
+		Mapper:      mapper,
+		ExpectedVal: TimeStringUser{ID: 1},
+	})
+
+	RunMapperTest(t, "not a time field", MapperTest[TimeStringUser]{
+		row: &Row{
+			columns: columnNames("id"),
+		},
+		scanned:             []any{1},
+		Mapper:              StructMapper[TimeStringUser](WithTimeLayout("id", time.RFC3339)),
+		ExpectedBeforeError: createError(nil, "not a time field", "id"),
+		ExpectedAfterError:  createError(nil, "not a time field", "id"),
+	})
+
+	dateMapper := StructMapper[DateUser](
+		WithTimeLayout("birthday", time.RFC3339),
+		WithTimeLayout("anniversary", time.RFC3339),
+	)
+	before, after := dateMapper(context.Background(), columnNames("birthday", "anniversary"))
+	row := &Row{
+		columns:          columnNames("birthday", "anniversary"),
+		scanDestinations: make([]reflect.Value, 2),
+	}
+
+	link, err := before(row)
+	if err != nil {
+		t.Fatalf("error in before: %v", err)
+	}
+	row.scanDestinations[0].Elem().Set(reflect.ValueOf(sql.NullString{String: "2023-01-02T03:04:05Z", Valid: true}))
+	row.scanDestinations[1].Elem().Set(reflect.ValueOf(sql.NullString{String: "2023-01-02T03:04:05Z", Valid: true}))
+
+	user, err := after(link)
+	if err != nil {
+		t.Fatalf("error in after: %v", err)
+	}
+
+	if !time.Time(user.Birthday).Equal(created) {
+		t.Fatalf("expected birthday %v, got %v", created, time.Time(user.Birthday))
+	}
+
+	if user.Anniversary == nil || !time.Time(*user.Anniversary).Equal(created) {
+		t.Fatalf("expected anniversary %v, got %v", created, user.Anniversary)
+	}
+
+	RunMapperTest(t, "invalid", MapperTest[TimeStringUser]{
+		row: &Row{
+			columns: columnNames("id", "created_at"),
+		},
+		scanned:            []any{1, sql.NullString{String: "yesterday", Valid: true}},
+		Mapper:             mapper,
+		ExpectedAfterError: createError(nil, "invalid time", "created_at"),
+	})
+}
+
+func TestEpochTimeColumns(t *testing.T) {
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	mapper := StructMapper[EpochUser](WithEpochTimeColumns("created_at", "updated_at", "seen_at"))
+
+	RunMapperTest(t, "seconds", MapperTest[EpochUser]{
+		row: &Row{
+			columns: columnNames("id", "created_at", "updated_at", "seen_at"),
+		},
+		scanned: []any{
+			1,
+			sql.NullInt64{Int64: created.Unix(), Valid: true},
+			sql.NullInt64{Int64: created.Unix(), Valid: true},
+			sql.NullTime{Time: created, Valid: true},
+		},
+		Mapper:      mapper,
+		ExpectedVal: EpochUser{ID: 1, CreatedAt: created, UpdatedAt: &created, SeenAt: created.Unix()},
+	})
+
+	RunMapperTest(t, "null", MapperTest[EpochUser]{
+		row: &Row{
+			columns: columnNames("id", "created_at", "updated_at", "seen_at"),
+		},
+		scanned:     []any{1, sql.NullInt64{}, sql.NullInt64{}, sql.NullTime{}},
+		Mapper:      mapper,
+		ExpectedVal: EpochUser{ID: 1},
+	})
+
+	withMillis := created.Add(123 * time.Millisecond)
+	RunMapperTest(t, "milliseconds", MapperTest[EpochUser]{
+		row: &Row{
+			columns: columnNames("id", "created_at", "updated_at", "seen_at"),
+		},
+		scanned: []any{
+			1,
+			sql.NullInt64{Int64: withMillis.UnixNano() / int64(time.Millisecond), Valid: true},
+			sql.NullInt64{Int64: withMillis.UnixNano() / int64(time.Millisecond), Valid: true},
+			sql.NullTime{Time: withMillis, Valid: true},
+		},
+		Mapper:      StructMapper[EpochUser](WithEpochMilliTimeColumns("created_at", "updated_at", "seen_at")),
+		ExpectedVal: EpochUser{ID: 1, CreatedAt: withMillis, UpdatedAt: &withMillis, SeenAt: withMillis.UnixNano() / int64(time.Millisecond)},
+	})
+
+	RunMapperTest(t, "negative milliseconds", MapperTest[EpochUser]{
+		row: &Row{
+			columns: columnNames("id", "created_at"),
+		},
+		scanned:     []any{1, sql.NullInt64{Int64: -1500, Valid: true}},
+		Mapper:      StructMapper[EpochUser](WithEpochMilliTimeColumns("created_at")),
+		ExpectedVal: EpochUser{ID: 1, CreatedAt: time.Unix(-2, 500*int64(time.Millisecond)).UTC()},
+	})
+
+	type narrowEpoch struct {
+		SeenAt   int32
+		ViewedAt uint32
+	}
+
+	RunMapperTest(t, "narrow fields", MapperTest[narrowEpoch]{
+		row: &Row{
+			columns: columnNames("seen_at", "viewed_at"),
+		},
+		scanned:     []any{sql.NullTime{Time: created, Valid: true}, sql.NullTime{Time: created, Valid: true}},
+		Mapper:      StructMapper[narrowEpoch](WithEpochTimeColumns("seen_at", "viewed_at")),
+		ExpectedVal: narrowEpoch{SeenAt: int32(created.Unix()), ViewedAt: uint32(created.Unix())},
+	})
+
+	// The epoch in milliseconds does not fit in an int32
+	RunMapperTest(t, "milliseconds overflow", MapperTest[narrowEpoch]{
+		row: &Row{
+			columns: columnNames("seen_at"),
+		},
+		scanned:            []any{sql.NullTime{Time: created, Valid: true}},
+		Mapper:             StructMapper[narrowEpoch](WithEpochMilliTimeColumns("seen_at")),
+		ExpectedAfterError: createError(nil, "invalid epoch", "seen_at"),
+	})
+
+	RunMapperTest(t, "negative unsigned", MapperTest[narrowEpoch]{
+		row: &Row{
+			columns: columnNames("viewed_at"),
+		},
+		scanned:            []any{sql.NullTime{Time: time.Unix(-10, 0), Valid: true}},
+		Mapper:             StructMapper[narrowEpoch](WithEpochTimeColumns("viewed_at")),
+		ExpectedAfterError: createError(nil, "invalid epoch", "viewed_at"),
+	})
+
+	RunMapperTest(t, "not an epoch field", MapperTest[SensitiveUser]{
+		row: &Row{
+			columns: columnNames("name"),
+		},
+		scanned:             []any{"foo"},
+		Mapper:              StructMapper[SensitiveUser](WithEpochTimeColumns("name")),
+		ExpectedBeforeError: createError(nil, "not an epoch field", "name"),
+		ExpectedAfterError:  createError(nil, "not an epoch field", "name"),
+	})
+}
+
+func TestTrimStringColumns(t *testing.T) {
+	name := "foo"
+	padded := func() *string {
+		s := "foo  "
+		return &s
+	}
+
+	RunMapperTest(t, "all", MapperTest[PaddedUser]{
+		row: &Row{
+			columns: columnNames("id", "code", "name", "note"),
+		},
+		scanned:     []any{1, "ab   ", padded(), " note \t\n"},
+		Mapper:      StructMapper[PaddedUser](WithTrimStringColumns()),
+		ExpectedVal: PaddedUser{ID: 1, Code: "ab", Name: &name, Note: " note"},
+	})
+
+	RunMapperTest(t, "columns", MapperTest[PaddedUser]{
+		row: &Row{
+			columns: columnNames("id", "code", "name", "note"),
+		},
+		scanned:     []any{1, "ab   ", padded(), "note  "},
+		Mapper:      StructMapper[PaddedUser](WithTrimStringColumns("code", "name")),
+		ExpectedVal: PaddedUser{ID: 1, Code: "ab", Name: &name, Note: "note  "},
+	})
+
+	RunMapperTest(t, "nil pointer", MapperTest[PaddedUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned:     []any{1, (*string)(nil)},
+		Mapper:      StructMapper[PaddedUser](WithTrimStringColumns()),
+		ExpectedVal: PaddedUser{ID: 1},
+	})
+
+	RunMapperTest(t, "not a string field", MapperTest[PaddedUser]{
+		row: &Row{
+			columns: columnNames("id"),
+		},
+		scanned:             []any{1},
+		Mapper:              StructMapper[PaddedUser](WithTrimStringColumns("id")),
+		ExpectedBeforeError: createError(nil, "not a string field", "id"),
+		ExpectedAfterError:  createError(nil, "not a string field", "id"),
+	})
+}
+
+func TestHstoreColumns(t *testing.T) {
+	mapper := StructMapper[HstoreUser](WithHstoreColumns("attrs", "tags"))
+	blue := "blue"
+
+	RunMapperTest(t, "valid", MapperTest[HstoreUser]{
+		row: &Row{
+			columns: columnNames("id", "attrs", "tags"),
+		},
+		scanned: []any{
+			1,
+			sql.NullString{String: `"color"=>"blue", "size"=>NULL`, Valid: true},
+			sql.NullString{String: `"color"=>"blue", "size"=>NULL`, Valid: true},
+		},
+		Mapper: mapper,
+		ExpectedVal: HstoreUser{
+			ID:    1,
+			Attrs: map[string]string{"color": "blue", "size": ""},
+			Tags:  &map[string]*string{"color": &blue, "size": nil},
+		},
+	})
+
+	RunMapperTest(t, "null", MapperTest[HstoreUser]{
+		row: &Row{
+			columns: columnNames("id", "attrs", "tags"),
+		},
+		scanned:     []any{1, sql.NullString{}, sql.NullString{}},
+		Mapper:      mapper,
+		ExpectedVal: HstoreUser{ID: 1},
+	})
+
+	RunMapperTest(t, "invalid", MapperTest[HstoreUser]{
+		row: &Row{
+			columns: columnNames("id", "attrs"),
+		},
+		scanned:            []any{1, sql.NullString{String: `"color"=>`, Valid: true}},
+		Mapper:             mapper,
+		ExpectedAfterError: createError(nil, "invalid hstore", "attrs"),
+	})
+
+	RunMapperTest(t, "not an hstore field", MapperTest[HstoreUser]{
+		row: &Row{
+			columns: columnNames("id"),
+		},
+		scanned:             []any{1},
+		Mapper:              StructMapper[HstoreUser](WithHstoreColumns("id")),
+		ExpectedBeforeError: createError(nil, "not an hstore field", "id"),
+		ExpectedAfterError:  createError(nil, "not an hstore field", "id"),
+	})
+}
+
+// valueComparers compare the types with unexported fields used in the tests
+var valueComparers = cmp.Options{
+	cmp.Comparer(func(a, b netip.Addr) bool {
+		return a == b
+	}),
+	cmp.Comparer(func(a, b big.Int) bool {
+		return a.Cmp(&b) == 0
+	}),
+	cmp.Comparer(func(a, b big.Rat) bool {
+		return a.Cmp(&b) == 0
+	}),
+}
+
+func TestBigNumberColumns(t *testing.T) {
+	bigInt := func(s string) *big.Int {
+		i, _ := new(big.Int).SetString(s, 10)
+		return i
+	}
+
+	bigRat := func(s string) *big.Rat {
+		r, _ := new(big.Rat).SetString(s)
+		return r
+	}
+
+	text := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: true}
+	}
+
+	for name, mapper := range map[string]Mapper[AmountUser]{
+		"all":   StructMapper[AmountUser](WithBigNumberColumns()),
+		"named": StructMapper[AmountUser](WithBigNumberColumns("balance", "limit", "ratio", "rate")),
+	} {
+		RunMapperTest(t, name, MapperTest[AmountUser]{
+			row: &Row{
+				columns: columnNames("id", "balance", "limit", "ratio", "rate"),
+			},
+			scanned: []any{
+				1,
+				text("-123456789012345678901234567890"),
+				text("12.00"),
+				text("-0.125"),
+				text("1/3"),
+			},
+			Mapper: mapper,
+			ExpectedVal: AmountUser{
+				ID:      1,
+				Balance: *bigInt("-123456789012345678901234567890"),
+				Limit:   big.NewInt(12),
+				Ratio:   *big.NewRat(-1, 8),
+				Rate:    big.NewRat(1, 3),
+			},
+		})
+	}
+
+	RunMapperTest(t, "exponent", MapperTest[AmountUser]{
+		row: &Row{
+			columns: columnNames("balance", "ratio"),
+		},
+		scanned:     []any{text("1e21"), text("-2.5e-3")},
+		Mapper:      StructMapper[AmountUser](WithBigNumberColumns()),
+		ExpectedVal: AmountUser{Balance: *bigInt("1000000000000000000000"), Ratio: *bigRat("-0.0025")},
+	})
+
+	RunMapperTest(t, "null", MapperTest[AmountUser]{
+		row: &Row{
+			columns: columnNames("id", "balance", "limit", "ratio", "rate"),
+		},
+		scanned:     []any{1, sql.NullString{}, sql.NullString{}, sql.NullString{}, sql.NullString{}},
+		Mapper:      StructMapper[AmountUser](WithBigNumberColumns()),
+		ExpectedVal: AmountUser{ID: 1},
+	})
+
+	RunMapperTest(t, "not an integer", MapperTest[AmountUser]{
+		row: &Row{
+			columns: columnNames("id", "balance"),
+		},
+		scanned:            []any{1, text("12.5")},
+		Mapper:             StructMapper[AmountUser](WithBigNumberColumns()),
+		ExpectedAfterError: createError(nil, "invalid big number", "balance"),
+	})
+
+	RunMapperTest(t, "invalid", MapperTest[AmountUser]{
+		row: &Row{
+			columns: columnNames("id", "rate"),
+		},
+		scanned:            []any{1, text("NaN")},
+		Mapper:             StructMapper[AmountUser](WithBigNumberColumns()),
+		ExpectedAfterError: createError(nil, "invalid big number", "rate"),
+	})
+
+	RunMapperTest(t, "not a big number field", MapperTest[AmountUser]{
+		row: &Row{
+			columns: columnNames("id"),
+		},
+		scanned:             []any{1},
+		Mapper:              StructMapper[AmountUser](WithBigNumberColumns("id")),
+		ExpectedBeforeError: createError(nil, "not a big number field", "id"),
+		ExpectedAfterError:  createError(nil, "not a big number field", "id"),
+	})
+}
+
+func TestTextUnmarshalerColumns(t *testing.T) {
+	gateway := netip.MustParseAddr("10.0.0.1")
+
+	for name, mapper := range map[string]Mapper[HostUser]{
+		"all":   StructMapper[HostUser](WithTextUnmarshalerColumns()),
+		"named": StructMapper[HostUser](WithTextUnmarshalerColumns("addr", "gateway")),
+	} {
+		RunMapperTest(t, name, MapperTest[HostUser]{
+			row: &Row{
+				columns: columnNames("id", "addr", "gateway"),
+			},
+			scanned: []any{
+				1,
+				sql.NullString{String: "192.168.0.1", Valid: true},
+				sql.NullString{String: "10.0.0.1", Valid: true},
+			},
+			Mapper: mapper,
+			ExpectedVal: HostUser{
+				ID:      1,
+				Addr:    netip.MustParseAddr("192.168.0.1"),
+				Gateway: &gateway,
+			},
+		})
+	}
+
+	RunMapperTest(t, "null", MapperTest[HostUser]{
+		row: &Row{
+			columns: columnNames("id", "addr", "gateway"),
+		},
+		scanned:     []any{1, sql.NullString{}, sql.NullString{}},
+		Mapper:      StructMapper[HostUser](WithTextUnmarshalerColumns()),
+		ExpectedVal: HostUser{ID: 1},
+	})
+
+	RunMapperTest(t, "invalid", MapperTest[HostUser]{
+		row: &Row{
+			columns: columnNames("id", "addr"),
+		},
+		scanned:            []any{1, sql.NullString{String: "not an address", Valid: true}},
+		Mapper:             StructMapper[HostUser](WithTextUnmarshalerColumns()),
+		ExpectedAfterError: createError(nil, "invalid text", "addr"),
+	})
+
+	RunMapperTest(t, "not a text unmarshaler field", MapperTest[HostUser]{
+		row: &Row{
+			columns: columnNames("id"),
+		},
+		scanned:             []any{1},
+		Mapper:              StructMapper[HostUser](WithTextUnmarshalerColumns("id")),
+		ExpectedBeforeError: createError(nil, "not a text unmarshaler field", "id"),
+		ExpectedAfterError:  createError(nil, "not a text unmarshaler field", "id"),
+	})
+
+	// Time fields implement encoding.TextUnmarshaler but
+	// are only unmarshaled from text if they are named
+	RunMapperTest(t, "time", MapperTest[TimeStringUser]{
+		row: &Row{
+			columns: columnNames("id", "created_at"),
+		},
+		scanned:     []any{1, now},
+		Mapper:      StructMapper[TimeStringUser](WithTextUnmarshalerColumns()),
+		ExpectedVal: TimeStringUser{ID: 1, CreatedAt: now},
+	})
+}
+
+func TestContextFieldOverrides(t *testing.T) {
+	RunMapperTest(t, "redacted", MapperTest[*SensitiveUser]{
+		row: &Row{
+			columns: columnNames("id", "name", "ssn"),
+		},
+		scanned: []any{1, "The Name", "123-45-6789"},
+		Mapper:  StructMapper[*SensitiveUser](WithContextFieldOverrides()),
+		Context: map[contextKey]any{
+			CtxKeyFieldOverrides: map[string]any{"ssn": "REDACTED"},
+		},
+		ExpectedVal: &SensitiveUser{ID: 1, Name: "The Name", SSN: "REDACTED"},
+	})
+
+	RunMapperTest(t, "not enabled", MapperTest[SensitiveUser]{
+		row: &Row{
+			columns: columnNames("id", "name", "ssn"),
+		},
+		scanned: []any{1, "The Name", "123-45-6789"},
+		Mapper:  StructMapper[SensitiveUser](),
+		Context: map[contextKey]any{
+			CtxKeyFieldOverrides: map[string]any{"ssn": "REDACTED"},
+		},
+		ExpectedVal: SensitiveUser{ID: 1, Name: "The Name", SSN: "123-45-6789"},
+	})
+
+	RunMapperTest(t, "wrong type", MapperTest[SensitiveUser]{
+		row: &Row{
+			columns: columnNames("id", "name", "ssn"),
+		},
+		scanned: []any{1, "The Name", "123-45-6789"},
+		Mapper:  StructMapper[SensitiveUser](WithContextFieldOverrides()),
+		Context: map[contextKey]any{
+			CtxKeyFieldOverrides: map[string]any{"ssn": 0},
+		},
+		ExpectedBeforeError: createError(nil, "invalid override", "ssn"),
+		ExpectedAfterError:  createError(nil, "invalid override", "ssn"),
+	})
+}
+
+func TestInterfaceFactory(t *testing.T) {
+	RunCustomStructMapperTest(t, "registered on source", CustomStructMapperTest[NamedUser]{
+		MapperTest: MapperTest[NamedUser]{
+			row: &Row{
+				columns: columnNames("id", "name"),
+			},
+			scanned:     []any{1, StringName("The Name")},
+			ExpectedVal: NamedUser{ID: 1, Name: toPtr(StringName("The Name"))},
+		},
+		Options: []MappingSourceOption{
+			WithSourceInterfaceFactory((*Named)(nil), func() any { return new(StringName) }),
+		},
+	})
+
+	RunCustomStructMapperTest(t, "not implemented", CustomStructMapperTest[NamedUser]{
+		MapperTest: MapperTest[NamedUser]{
+			row: &Row{
+				columns: columnNames("id", "name"),
+			},
+			scanned:            []any{1, 2},
+			ExpectedAfterError: createError(nil, "invalid interface factory", "name"),
+		},
+		Options: []MappingSourceOption{
+			WithSourceInterfaceFactory((*Named)(nil), func() any { return new(int) }),
+		},
+	})
+
+	RunMapperTest(t, "not registered", MapperTest[NamedUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned:             []any{1, StringName("The Name")},
+		Mapper:              StructMapper[NamedUser](),
+		ExpectedBeforeError: createError(nil, "no interface factory", "name"),
+		ExpectedAfterError:  createError(nil, "no interface factory", "name"),
+	})
+
+	for _, iface := range []any{nil, Named(nil), new(StringName)} {
+		if _, err := NewStructMapperSource(WithSourceInterfaceFactory(iface, func() any { return nil })); err == nil {
+			t.Fatalf("expected an error for %T", iface)
+		}
+	}
+}
+
+func TestArgField(t *testing.T) {
+	RunMapperTest(t, "set from args", MapperTest[*TenantUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned: []any{1, "The Name"},
+		Mapper:  StructMapper[*TenantUser](WithArgField("tenant_id", 1)),
+		Context: map[contextKey]any{
+			ctxKeyQueryArgs: []any{"ignored", int64(5)},
+		},
+		ExpectedVal: &TenantUser{ID: 1, Name: "The Name", TenantID: 5},
+	})
+
+	RunMapperTest(t, "context overrides win", MapperTest[TenantUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned: []any{1, "The Name"},
+		Mapper:  StructMapper[TenantUser](WithArgField("tenant_id", 0), WithContextFieldOverrides()),
+		Context: map[contextKey]any{
+			ctxKeyQueryArgs:      []any{int64(5)},
+			CtxKeyFieldOverrides: map[string]any{"tenant_id": int64(7)},
+		},
+		ExpectedVal: TenantUser{ID: 1, Name: "The Name", TenantID: 7},
+	})
+
+	RunMapperTest(t, "no args", MapperTest[TenantUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned:             []any{1, "The Name"},
+		Mapper:              StructMapper[TenantUser](WithArgField("tenant_id", 0)),
+		ExpectedBeforeError: createError(nil, "arg index out of range", "tenant_id"),
+		ExpectedAfterError:  createError(nil, "arg index out of range", "tenant_id"),
+	})
+
+	RunMapperTest(t, "unknown field", MapperTest[TenantUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned: []any{1, "The Name"},
+		Mapper:  StructMapper[TenantUser](WithArgField("tenant", 0)),
+		Context: map[contextKey]any{
+			ctxKeyQueryArgs: []any{int64(5)},
+		},
+		ExpectedBeforeError: createError(nil, "unknown arg field", "tenant"),
+		ExpectedAfterError:  createError(nil, "unknown arg field", "tenant"),
+	})
+
+	RunMapperTest(t, "wrong type", MapperTest[TenantUser]{
+		row: &Row{
+			columns: columnNames("id", "name"),
+		},
+		scanned: []any{1, "The Name"},
+		Mapper:  StructMapper[TenantUser](WithArgField("tenant_id", 0)),
+		Context: map[contextKey]any{
+			ctxKeyQueryArgs: []any{"5"},
+		},
+		ExpectedBeforeError: createError(nil, "invalid arg field", "tenant_id"),
+		ExpectedAfterError:  createError(nil, "invalid arg field", "tenant_id"),
+	})
+}
+
+func TestNilOnAllNull(t *testing.T) {
+	RunMapperTest(t, "all null", MapperTest[UserWithPost]{
+		row: &Row{
+			columns: columnNames("id", "name", "post.id", "post.title"),
+		},
+		scanned:     []any{1, "The Name", (*int)(nil), (*string)(nil)},
+		Mapper:      StructMapper[UserWithPost](WithNilOnAllNull("Post")),
+		ExpectedVal: UserWithPost{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "some null", MapperTest[UserWithPost]{
+		row: &Row{
+			columns: columnNames("id", "name", "post.id", "post.title"),
+		},
+		scanned:     []any{1, "The Name", toPtr(2), (*string)(nil)},
+		Mapper:      StructMapper[UserWithPost](WithNilOnAllNull("Post")),
+		ExpectedVal: UserWithPost{ID: 1, Name: "The Name", Post: &JoinedPost{ID: 2}},
+	})
+
+	RunMapperTest(t, "not null", MapperTest[*UserWithPost]{
+		row: &Row{
+			columns: columnNames("id", "name", "post.id", "post.title"),
+		},
+		scanned:     []any{1, "The Name", toPtr(2), toPtr("The Title")},
+		Mapper:      StructMapper[*UserWithPost](WithNilOnAllNull("Post")),
+		ExpectedVal: &UserWithPost{ID: 1, Name: "The Name", Post: &JoinedPost{ID: 2, Title: "The Title"}},
+	})
+
+	RunMapperTest(t, "all null with null type coercion", MapperTest[UserWithPost]{
+		row: &Row{
+			columns: columnNames("id", "name", "post.id", "post.title"),
+		},
+		scanned:     []any{sql.NullInt64{Int64: 1, Valid: true}, sql.NullString{String: "The Name", Valid: true}, sql.NullInt64{}, sql.NullString{}},
+		Mapper:      StructMapper[UserWithPost](WithNilOnAllNull("Post"), WithNullTypeCoercion()),
+		ExpectedVal: UserWithPost{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "some null with null type coercion", MapperTest[UserWithPost]{
+		row: &Row{
+			columns: columnNames("id", "name", "post.id", "post.title"),
+		},
+		scanned:     []any{sql.NullInt64{Int64: 1, Valid: true}, sql.NullString{String: "The Name", Valid: true}, sql.NullInt64{Int64: 2, Valid: true}, sql.NullString{}},
+		Mapper:      StructMapper[UserWithPost](WithNilOnAllNull("Post"), WithNullTypeCoercion()),
+		ExpectedVal: UserWithPost{ID: 1, Name: "The Name", Post: &JoinedPost{ID: 2}},
+	})
+
+	for _, path := range []string{"Missing", "Name", "Post.ID"} {
+		RunMapperTest(t, "invalid path "+path, MapperTest[UserWithPost]{
+			row: &Row{
+				columns: columnNames("id"),
+			},
+			Mapper:              StructMapper[UserWithPost](WithNilOnAllNull(path)),
+			ExpectedBeforeError: createError(nil, "invalid nil on all null path"),
+			ExpectedAfterError:  createError(nil, "invalid nil on all null path"),
+		})
+	}
+}
+
+func TestColumns(t *testing.T) {
+	cols, err := Columns[*PtrUser2](defaultStructMapper)
+	if err != nil {
+		t.Fatalf("couldn't get columns: %v", err)
+	}
+
+	expected := []string{"id", "name", "created_at", "updated_at"}
+	if diff := cmp.Diff(expected, cols); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	cols, err = Columns[PtrPtrUser](defaultStructMapper)
+	if err != nil {
+		t.Fatalf("couldn't get columns: %v", err)
+	}
+
+	expected = []string{"id", "name", "owner.id", "owner.name"}
+	if diff := cmp.Diff(expected, cols); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	src, err := NewStructMapperSource(WithStructTagKey("custom"), WithFieldNameMapper(strings.ToUpper))
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+
+	cols, err = Columns[Tagged](src)
+	if err != nil {
+		t.Fatalf("couldn't get columns: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"custom_id", "custom_name", "EMAIL"}, cols); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if _, err := Columns[int](src); err == nil {
+		t.Fatal("expected error for non-struct type")
+	}
+}
+
+func TestAnonymousStruct(t *testing.T) {
+	type anonUser = struct {
+		ID    int
+		Name  string
+		Owner *struct{ ID int }
+		Code  string `db:"user_code"`
+	}
+
+	RunMapperTest(t, "anonymous", MapperTest[anonUser]{
+		row: &Row{
+			columns: columnNames("id", "name", "owner.id", "user_code"),
+		},
+		scanned: []any{1, "The Name", 2, "abc"},
+		Mapper:  StructMapper[anonUser](),
+		ExpectedVal: anonUser{
+			ID: 1, Name: "The Name",
+			Owner: &struct{ ID int }{ID: 2},
+			Code:  "abc",
+		},
+	})
+
+	RunMapperTest(t, "anonymous pointer", MapperTest[*struct{ ID int }]{
+		row: &Row{
+			columns: columnNames("id"),
+		},
+		scanned:     []any{1},
+		Mapper:      StructMapper[*struct{ ID int }](),
+		ExpectedVal: &struct{ ID int }{ID: 1},
+	})
+
+	src, err := NewStructMapperSource()
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+
+	cols, err := Columns[anonUser](src)
+	if err != nil {
+		t.Fatalf("couldn't get columns: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"id", "name", "owner.id", "user_code"}, cols); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	// Identical anonymous structs are the same type and share the cached mapping,
+	// while a different tag makes a different type
+	if _, err := Columns[struct{ ID int }](src); err != nil {
+		t.Fatalf("couldn't get columns: %v", err)
+	}
+
+	cols, err = Columns[struct {
+		ID int `db:"user_id"`
+	}](src)
+	if err != nil {
+		t.Fatalf("couldn't get columns: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"user_id"}, cols); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	impl := src.(*mapperSourceImpl)
+	if _, ok := impl.cached(typeOf[struct{ ID int }]()); !ok {
+		t.Fatal("anonymous struct mapping was not cached")
+	}
+
+	if len(impl.cache) != 3 {
+		t.Fatalf("expected 3 cached mappings, got %d", len(impl.cache))
+	}
+}
+
+func TestColumnsOrder(t *testing.T) {
+	expected := []string{"id", "created_at", "updated_at", "owner.id", "owner.name", "name", "pet_id"}
+
+	// Every source builds the mapping again, so the
+	// order must not depend on the cache or the run
+	for i := 0; i < 10; i++ {
+		src, err := NewStructMapperSource()
+		if err != nil {
+			t.Fatalf("couldn't get mapper source: %v", err)
+		}
+
+		cols, err := Columns[OrderedUser](src)
+		if err != nil {
+			t.Fatalf("couldn't get columns: %v", err)
+		}
+
+		if diff := cmp.Diff(expected, cols); diff != "" {
+			t.Fatalf("diff: %s", diff)
+		}
+
+		cols, err = Columns[*OrderedUser](src)
+		if err != nil {
+			t.Fatalf("couldn't get columns: %v", err)
+		}
+
+		if diff := cmp.Diff(expected, cols); diff != "" {
+			t.Fatalf("diff: %s", diff)
+		}
+	}
+}
+
+func TestMapperFromSource(t *testing.T) {
+	src, err := NewStructMapperSource(WithStructTagKey("custom"))
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+
+	m, cols, err := MapperFromSource[*Tagged](src)
+	if err != nil {
+		t.Fatalf("couldn't get mapper: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"custom_id", "custom_name", "email"}, cols); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	RunMapperTest(t, "from source", MapperTest[*Tagged]{
+		row: &Row{
+			columns: columnNames("custom_id", "custom_name", "email"),
+		},
+		scanned:     []any{1, "The Name", "user@example.com"},
+		Mapper:      m,
+		ExpectedVal: &Tagged{ID: 1, Name: "The Name", Email: "user@example.com"},
+	})
+
+	_, cols, err = MapperFromSource[OrderedUser](defaultStructMapper, WithColumnSeparatorOverride("__"))
+	if err != nil {
+		t.Fatalf("couldn't get mapper: %v", err)
+	}
+
+	expected := []string{"id", "created_at", "updated_at", "owner__id", "owner__name", "name", "pet_id"}
+	if diff := cmp.Diff(expected, cols); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if _, _, err := MapperFromSource[int](src); err == nil {
+		t.Fatal("expected error for non-struct type")
+	}
+}
+
+func TestPositionalFallback(t *testing.T) {
+	RunMapperTest(t, "all empty", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("", ""),
+		},
+		scanned:     []any{1, "The Name"},
+		Mapper:      StructMapper[User](WithPositionalFallback()),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "some empty", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("id", ""),
+		},
+		scanned:     []any{1, "The Name"},
+		Mapper:      StructMapper[User](WithPositionalFallback()),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	// The first field already has a column, so the empty one is not bound
+	RunMapperTest(t, "field has a column", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("", "id"),
+		},
+		scanned:     []any{2, 1},
+		Mapper:      StructMapper[User](WithPositionalFallback()),
+		ExpectedVal: User{ID: 1},
+	})
+
+	RunMapperTest(t, "more columns than fields", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("", "", ""),
+		},
+		scanned:     []any{1, "The Name", 3},
+		Mapper:      StructMapper[User](WithPositionalFallback()),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "with prefix", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("user_id", ""),
+		},
+		scanned:     []any{1, "The Name"},
+		Mapper:      StructMapper[User](WithStructTagPrefix("user_"), WithPositionalFallback()),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "with options", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("", ""),
+		},
+		scanned:     []any{toPtr(1), toPtr("The Name")},
+		Mapper:      StructMapper[User](WithNullAsZero(), WithPositionalFallback()),
+		ExpectedVal: User{ID: 1, Name: "The Name"},
+	})
+
+	RunMapperTest(t, "without fallback", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("", ""),
+		},
+		scanned:     []any{1, "The Name"},
+		Mapper:      StructMapper[User](),
+		ExpectedVal: User{},
+	})
+
+	// The aliased column is the column of the first field
+	RunMapperTest(t, "field has an aliased column", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("", "identifier"),
+		},
+		scanned:     []any{2, 1},
+		Mapper:      StructMapper[User](WithColumnAliases(map[string]string{"identifier": "id"}), WithPositionalFallback()),
+		ExpectedVal: User{ID: 1},
+	})
+
+	src, err := NewStructMapperSource(WithColumnTransformer(strings.ToLower))
+	if err != nil {
+		t.Fatalf("could not create source: %v", err)
+	}
+
+	RunMapperTest(t, "field has a transformed column", MapperTest[User]{
+		row: &Row{
+			columns: columnNames("", "ID"),
+		},
+		scanned:     []any{2, 1},
+		Mapper:      CustomStructMapper[User](src, WithPositionalFallback()),
+		ExpectedVal: User{ID: 1},
+	})
+
+	t.Run("columns are not renamed", func(t *testing.T) {
+		row := &Row{
+			columns:          columnNames("", ""),
+			scanDestinations: make([]reflect.Value, 2),
+		}
+
+		before, _ := StructMapper[User](WithPositionalFallback())(context.Background(), row.columnsCopy())
+		if _, err := before(row); err != nil {
+			t.Fatalf("error scheduling scans: %v", err)
+		}
+
+		if diff := cmp.Diff([]string{"", ""}, row.columns); diff != "" {
+			t.Fatalf("diff: %s", diff)
+		}
+
+		if _, err := row.createTargets(); err != nil {
+			t.Fatalf("expected every column to have a destination: %v", err)
+		}
+	})
+}
+
+func TestSplitTagOption(t *testing.T) {
+	RunMapperTest(t, "split", MapperTest[SplitUser]{
+		row: &Row{
+			columns: columnNames("id", "tags", "roles"),
+		},
+		scanned: []any{
+			1,
+			sql.NullString{String: "a, b,c", Valid: true},
+			sql.NullString{String: "admin | user", Valid: true},
+		},
+		Mapper: StructMapper[SplitUser](),
+		ExpectedVal: SplitUser{
+			ID:    1,
+			Tags:  []string{"a", "b", "c"},
+			Roles: []string{"admin", "user"},
+		},
+	})
+
+	RunMapperTest(t, "empty and null", MapperTest[SplitUser]{
+		row: &Row{
+			columns: columnNames("id", "tags", "roles"),
+		},
+		scanned:     []any{1, sql.NullString{Valid: true}, sql.NullString{}},
+		Mapper:      StructMapper[SplitUser](),
+		ExpectedVal: SplitUser{ID: 1, Tags: []string{}},
+	})
+
+	RunMapperTest(t, "not a string slice", MapperTest[BadSplitUser]{
+		row: &Row{
+			columns: columnNames("tags"),
+		},
+		scanned:             []any{sql.NullString{}},
+		Mapper:              StructMapper[BadSplitUser](),
+		ExpectedBeforeError: createError(nil, "not a string slice field", "tags"),
+		ExpectedAfterError:  createError(nil, "not a string slice field", "tags"),
+	})
+}
+
+func TestMappingErrorMessage(t *testing.T) {
+	cause := errors.New("the cause")
+	err := createError(cause, "no destination", "name")
+	if err.Error() != "the cause" {
+		t.Fatalf("unexpected message: %q", err.Error())
+	}
+
+	err = createError(nil, "no destination", "name")
+	if err.Error() != "mapping error: no destination, name" {
+		t.Fatalf("unexpected message: %q", err.Error())
+	}
+
+	if errors.Unwrap(err) != nil {
+		t.Fatal("expected a nil cause")
+	}
+
+	err = createError(nil)
+	if err.Error() != "mapping error" {
+		t.Fatalf("unexpected message: %q", err.Error())
+	}
+}
+
+func TestFieldStrategyPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      reflect.Type
+		column   string
+		opts     []MappingOption
+		expected reflect.Type
+	}{
+		{
+			name:   "builder before time layout",
+			typ:    typeOf[TimeStringUser](),
+			column: "created_at",
+			opts: []MappingOption{
+				WithTimeLayout("created_at", time.RFC3339),
+				WithFieldBuilder("created_at", func(current, scanned any) any { return current }),
+			},
+			expected: typeOf[*any](),
+		},
+		{
+			name:   "time layout before epoch",
+			typ:    typeOf[TimeStringUser](),
+			column: "created_at",
+			opts: []MappingOption{
+				WithEpochTimeColumns("created_at"),
+				WithTimeLayout("created_at", time.RFC3339),
+			},
+			expected: typeOf[*sql.NullString](),
+		},
+		{
+			name:     "epoch before null coercion",
+			typ:      typeOf[TimeStringUser](),
+			column:   "created_at",
+			opts:     []MappingOption{WithNullTypeCoercion(), WithEpochTimeColumns("created_at")},
+			expected: typeOf[*sql.NullInt64](),
+		},
+		{
+			name:     "json before big number",
+			typ:      typeOf[AmountUser](),
+			column:   "balance",
+			opts:     []MappingOption{WithBigNumberColumns(), WithJSONColumns("balance")},
+			expected: typeOf[*[]byte](),
+		},
+		{
+			name:     "converter before null coercion",
+			typ:      typeOf[User](),
+			column:   "name",
+			opts:     []MappingOption{WithNullTypeCoercion(), WithTypeConverter(typeConverter{})},
+			expected: typeOf[*wrapper](),
+		},
+		{
+			name:     "trimming keeps the strategy",
+			typ:      typeOf[User](),
+			column:   "name",
+			opts:     []MappingOption{WithTrimStringColumns(), WithNullTypeCoercion()},
+			expected: typeOf[*sql.NullString](),
+		},
+		{
+			name:     "nullable without options",
+			typ:      typeOf[User](),
+			column:   "name",
+			opts:     []MappingOption{WithNullAsZero()},
+			expected: typeOf[**string](),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := defaultStructMapper.getMapping(tc.typ)
+			if err != nil {
+				t.Fatalf("could not get mapping: %v", err)
+			}
+
+			var filtered mapping
+			for _, info := range m {
+				if info.name == tc.column {
+					filtered = append(filtered, info)
+				}
+			}
+
+			opts := structMapperOptions(defaultStructMapper, tc.opts)
+			strategies, err := fieldStrategies(tc.typ, filtered, opts, func(int) bool { return opts.nullAsZero })
+			if err != nil {
+				t.Fatalf("could not resolve strategies: %v", err)
+			}
+
+			if got := strategies[0].dest().Type(); got != tc.expected {
+				t.Fatalf("expected destination of type %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestConvertKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      any
+		keyType  reflect.Type
+		expected any
+		err      error
+	}{
+		{name: "assignable to interface", key: 1, keyType: typeOf[any](), expected: 1},
+		{name: "named type", key: "a", keyType: typeOf[StringName](), expected: StringName("a")},
+		{name: "bytes to string", key: []byte("a"), keyType: typeOf[string](), expected: "a"},
+		{name: "int to string", key: int64(65), keyType: typeOf[string](), err: createError(nil, "invalid key type", "id")},
+		{name: "string to int", key: "1", keyType: typeOf[int](), err: createError(nil, "invalid key type", "id")},
+		{name: "narrowed int", key: int64(100), keyType: typeOf[int8](), expected: int8(100)},
+		{name: "int overflow", key: int64(300), keyType: typeOf[int8](), err: createError(nil, "invalid key", "id")},
+		{name: "negative uint", key: -1, keyType: typeOf[uint](), err: createError(nil, "invalid key", "id")},
+		{name: "uint overflow", key: uint64(1 << 63), keyType: typeOf[int64](), err: createError(nil, "invalid key", "id")},
+		{name: "whole float", key: 2.0, keyType: typeOf[int](), expected: 2},
+		{name: "fraction", key: 1.5, keyType: typeOf[int](), err: createError(nil, "invalid key", "id")},
+		{name: "float precision", key: int64(1<<53 + 1), keyType: typeOf[float64](), err: createError(nil, "invalid key", "id")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := convertKey("id", reflect.ValueOf(tc.key), tc.keyType)
+			if diff := diffErr(tc.err, err); diff != "" {
+				t.Fatalf("diff: %s", diff)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(tc.expected, got.Interface()); diff != "" {
 				t.Fatalf("diff: %s", diff)
 			}
 		})
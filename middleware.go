@@ -0,0 +1,111 @@
+package scan
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// Middleware wraps a [Queryer] to add behaviour such as logging, tracing or retries.
+// [Debug] is an example of a middleware
+type Middleware = func(Queryer) Queryer
+
+// QueryerFunc is an adapter to use an ordinary function as a [Queryer].
+// It is useful for writing middlewares
+type QueryerFunc func(ctx context.Context, query string, args ...any) (Rows, error)
+
+// QueryContext calls f(ctx, query, args...)
+func (f QueryerFunc) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	return f(ctx, query, args...)
+}
+
+// Chain wraps q with the middlewares. The first middleware is the outermost,
+// so it is the first to receive the query and the last to receive the result.
+//
+//	exec := scan.Chain(db, retry, func(q scan.Queryer) scan.Queryer {
+//	    return scan.Debug(q, nil)
+//	})
+func Chain(q Queryer, mws ...Middleware) Queryer {
+	for i := len(mws) - 1; i >= 0; i-- {
+		q = mws[i](q)
+	}
+
+	return q
+}
+
+// RetryPolicy controls how [Retry] retries a query
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the query is run,
+	// including the first attempt. Values less than 1 are treated as 1
+	MaxAttempts int
+
+	// Backoff returns how long to wait before the given retry,
+	// which starts at 1 for the second attempt. If nil, there is no wait
+	Backoff func(retry int) time.Duration
+
+	// Retryable reports if the query should be run again after the error.
+	// If nil, only [driver.ErrBadConn] is retried
+	Retryable func(error) bool
+}
+
+// Retry runs the query again when it fails with an error that is retryable
+// according to the policy. Only the call to QueryContext is retried, errors that happen
+// while iterating the rows are not, since the rows may have already been consumed.
+// If the context is done while waiting, the error of the context is returned
+func Retry(q Queryer, policy RetryPolicy) Queryer {
+	return retryQueryer{q: q, policy: policy}
+}
+
+type retryQueryer struct {
+	q      Queryer
+	policy RetryPolicy
+}
+
+func (r retryQueryer) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	retryable := r.policy.Retryable
+	if retryable == nil {
+		retryable = func(err error) bool {
+			return errors.Is(err, driver.ErrBadConn)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		rows, err := r.q.QueryContext(ctx, query, args...)
+		if err == nil || attempt >= r.policy.MaxAttempts || !retryable(err) {
+			return rows, err
+		}
+
+		if r.policy.Backoff == nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			continue
+		}
+
+		timer := time.NewTimer(r.policy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// ExponentialBackoff returns a backoff for [RetryPolicy] that starts at base
+// and doubles for every retry up to max
+func ExponentialBackoff(base, max time.Duration) func(retry int) time.Duration {
+	return func(retry int) time.Duration {
+		d := base
+		for i := 1; i < retry && d < max; i++ {
+			d *= 2
+		}
+
+		if d > max {
+			return max
+		}
+
+		return d
+	}
+}
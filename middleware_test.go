@@ -0,0 +1,243 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var errTransient = errors.New("transient error")
+
+// flakyQueryer fails with err, or errTransient if it is nil,
+// until it has been called failures times
+type flakyQueryer struct {
+	calls    *int
+	failures int
+	err      error
+}
+
+func (f flakyQueryer) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	*f.calls++
+	if *f.calls <= f.failures {
+		if f.err != nil {
+			return nil, f.err
+		}
+		return nil, errTransient
+	}
+
+	return nil, nil
+}
+
+// retry is a middleware that retries queries that fail with errTransient
+func retry(attempts int) Middleware {
+	return func(q Queryer) Queryer {
+		return QueryerFunc(func(ctx context.Context, query string, args ...any) (Rows, error) {
+			var err error
+			for i := 0; i < attempts; i++ {
+				var rows Rows
+				rows, err = q.QueryContext(ctx, query, args...)
+				if !errors.Is(err, errTransient) {
+					return rows, err
+				}
+			}
+
+			return nil, err
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	record := func(name string) Middleware {
+		return func(q Queryer) Queryer {
+			return QueryerFunc(func(ctx context.Context, query string, args ...any) (Rows, error) {
+				order = append(order, name)
+				return q.QueryContext(ctx, query, args...)
+			})
+		}
+	}
+
+	exec := Chain(NoopQueryer{}, record("first"), record("second"), record("third"))
+	if _, err := exec.QueryContext(context.Background(), "A QUERY"); err != nil {
+		t.Fatalf("error running QueryContext: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"first", "second", "third"}, order); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if _, ok := Chain(NoopQueryer{}).(NoopQueryer); !ok {
+		t.Fatal("Chain without middlewares should return the queryer")
+	}
+}
+
+func TestChainRetry(t *testing.T) {
+	var calls int
+	dest := &bytes.Buffer{}
+
+	// the debug middleware is inside the retry so every attempt is printed
+	exec := Chain(flakyQueryer{calls: &calls, failures: 2}, retry(3), func(q Queryer) Queryer {
+		return Debug(q, dest)
+	})
+
+	if _, err := exec.QueryContext(context.Background(), "A QUERY", 1); err != nil {
+		t.Fatalf("error running QueryContext: %v", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+
+	if expected := "A QUERY\n[1]\nA QUERY\n[1]\nA QUERY\n[1]\n"; dest.String() != expected {
+		t.Fatalf("wrong debug output.\nExpected: %q\nGot: %q", expected, dest.String())
+	}
+
+	calls = 0
+	exec = Chain(flakyQueryer{calls: &calls, failures: 5}, retry(3))
+	if _, err := exec.QueryContext(context.Background(), "A QUERY"); !errors.Is(err, errTransient) {
+		t.Fatalf("expected the transient error, got %v", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	isTransient := func(err error) bool {
+		return errors.Is(err, errTransient)
+	}
+
+	t.Run("succeeds", func(t *testing.T) {
+		var calls int
+		var retries []int
+
+		exec := Retry(flakyQueryer{calls: &calls, failures: 2}, RetryPolicy{
+			MaxAttempts: 5,
+			Retryable:   isTransient,
+			Backoff: func(retry int) time.Duration {
+				retries = append(retries, retry)
+				return time.Millisecond
+			},
+		})
+
+		if _, err := exec.QueryContext(context.Background(), "A QUERY"); err != nil {
+			t.Fatalf("error running QueryContext: %v", err)
+		}
+
+		if calls != 3 {
+			t.Fatalf("expected 3 calls, got %d", calls)
+		}
+
+		if diff := cmp.Diff([]int{1, 2}, retries); diff != "" {
+			t.Fatalf("diff: %s", diff)
+		}
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		var calls int
+		exec := Retry(flakyQueryer{calls: &calls, failures: 5}, RetryPolicy{
+			MaxAttempts: 3,
+			Retryable:   isTransient,
+		})
+
+		if _, err := exec.QueryContext(context.Background(), "A QUERY"); !errors.Is(err, errTransient) {
+			t.Fatalf("expected the transient error, got %v", err)
+		}
+
+		if calls != 3 {
+			t.Fatalf("expected 3 calls, got %d", calls)
+		}
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		permanent := errors.New("permanent error")
+
+		var calls int
+		exec := Retry(flakyQueryer{calls: &calls, failures: 5, err: permanent}, RetryPolicy{
+			MaxAttempts: 3,
+			Retryable:   isTransient,
+		})
+
+		if _, err := exec.QueryContext(context.Background(), "A QUERY"); !errors.Is(err, permanent) {
+			t.Fatalf("expected the permanent error, got %v", err)
+		}
+
+		if calls != 1 {
+			t.Fatalf("expected 1 call, got %d", calls)
+		}
+	})
+
+	t.Run("bad connection by default", func(t *testing.T) {
+		var calls int
+		exec := Retry(flakyQueryer{calls: &calls, failures: 1, err: driver.ErrBadConn}, RetryPolicy{
+			MaxAttempts: 3,
+		})
+
+		if _, err := exec.QueryContext(context.Background(), "A QUERY"); err != nil {
+			t.Fatalf("error running QueryContext: %v", err)
+		}
+
+		if calls != 2 {
+			t.Fatalf("expected 2 calls, got %d", calls)
+		}
+
+		calls = 0
+		exec = Retry(flakyQueryer{calls: &calls, failures: 1}, RetryPolicy{MaxAttempts: 3})
+		if _, err := exec.QueryContext(context.Background(), "A QUERY"); !errors.Is(err, errTransient) {
+			t.Fatalf("expected the transient error, got %v", err)
+		}
+
+		if calls != 1 {
+			t.Fatalf("expected 1 call, got %d", calls)
+		}
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var calls int
+		exec := Retry(flakyQueryer{calls: &calls, failures: 5}, RetryPolicy{
+			MaxAttempts: 3,
+			Retryable:   isTransient,
+			Backoff: func(int) time.Duration {
+				cancel()
+				return time.Hour
+			},
+		})
+
+		if _, err := exec.QueryContext(ctx, "A QUERY"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+
+		if calls != 1 {
+			t.Fatalf("expected 1 call, got %d", calls)
+		}
+	})
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+
+	got := make([]time.Duration, 0, 5)
+	for retry := 1; retry <= 5; retry++ {
+		got = append(got, backoff(retry))
+	}
+
+	expected := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
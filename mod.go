@@ -53,3 +53,22 @@ func Mod[T any](m Mapper[T], mods ...MapperMod) Mapper[T] {
 			}
 	}
 }
+
+// MapResult converts a mapper into a mapper of another type by calling f
+// with the value mapped from each row. An error returned by f is returned
+// for the row
+func MapResult[A, B any](m Mapper[A], f func(A) (B, error)) Mapper[B] {
+	return func(ctx context.Context, c cols) (func(*Row) (any, error), func(any) (B, error)) {
+		before, after := m(ctx, c)
+
+		return before, func(link any) (B, error) {
+			a, err := after(link)
+			if err != nil {
+				var b B
+				return b, err
+			}
+
+			return f(a)
+		}
+	}
+}
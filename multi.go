@@ -0,0 +1,143 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrMultipleResultSetsNotSupported is returned when reading multiple result sets
+// from [Rows] that do not implement [MultiResultRows]
+var ErrMultipleResultSetsNotSupported = errors.New("rows do not support multiple result sets")
+
+// MultiResultRows can optionally be implemented by [Rows] that support multiple
+// result sets, such as *sql.Rows. It is used by [AllMulti] and [ResultSets]
+type MultiResultRows interface {
+	Rows
+	NextResultSet() bool
+}
+
+// ResultSet reads a single result set of a query with multiple result sets.
+// Use [Into] to create one
+type ResultSet func(context.Context, Rows) (int, error)
+
+// Into returns a [ResultSet] that maps all the rows of the result set with m
+// and appends them to dest
+func Into[T any](dest *[]T, m Mapper[T]) ResultSet {
+	return func(ctx context.Context, rows Rows) (int, error) {
+		vals, err := AllFromRows(ctx, m, rows)
+		*dest = append(*dest, vals...)
+		return len(vals), err
+	}
+}
+
+// AllMulti runs a query that returns multiple result sets, such as a stored procedure,
+// and maps the rows of every result set with m. It returns one slice per result set.
+// The mapper is generated again for each result set, so the columns can be different.
+//
+// It returns [ErrMultipleResultSetsNotSupported] if the [Rows] returned by the
+// [Queryer] do not implement [MultiResultRows]
+func AllMulti[T any](ctx context.Context, exec Queryer, m Mapper[T], query string, args ...any) ([][]T, error) {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		onComplete(ctx)(0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return AllMultiFromRows(withQueryArgs(ctx, args), m, rows)
+}
+
+// AllMultiFromRows works like [AllMulti] but reads the result sets from the given [Rows]
+func AllMultiFromRows[T any](ctx context.Context, m Mapper[T], rows Rows) ([][]T, error) {
+	var sets [][]T
+
+	err := eachResultSet(ctx, rows, func(ctx context.Context, _ int, rows Rows) (int, error) {
+		vals, err := AllFromRows(ctx, m, rows)
+		if err != nil {
+			return 0, err
+		}
+
+		sets = append(sets, vals)
+		return len(vals), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+// ResultSets runs a query that returns multiple result sets and reads them in order
+// with the given [ResultSet]s. This makes it possible to map each result set to a different type
+//
+//	var users []User
+//	var posts []Post
+//	err := scan.ResultSets(ctx, db, []scan.ResultSet{
+//	    scan.Into(&users, scan.StructMapper[User]()),
+//	    scan.Into(&posts, scan.StructMapper[Post]()),
+//	}, "CALL users_and_posts()")
+//
+// It returns an error if the number of result sets is different from the number of readers.
+// It returns [ErrMultipleResultSetsNotSupported] if the [Rows] returned by the
+// [Queryer] do not implement [MultiResultRows]
+func ResultSets(ctx context.Context, exec Queryer, sets []ResultSet, query string, args ...any) error {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		onComplete(ctx)(0, err)
+		return err
+	}
+	defer rows.Close()
+
+	return ResultSetsFromRows(withQueryArgs(ctx, args), sets, rows)
+}
+
+// ResultSetsFromRows works like [ResultSets] but reads the result sets from the given [Rows]
+func ResultSetsFromRows(ctx context.Context, sets []ResultSet, rows Rows) error {
+	var read int
+
+	err := eachResultSet(ctx, rows, func(ctx context.Context, i int, rows Rows) (int, error) {
+		if i >= len(sets) {
+			return 0, fmt.Errorf("expected %d result sets, got more", len(sets))
+		}
+
+		read++
+		return sets[i](ctx, rows)
+	})
+	if err != nil {
+		return err
+	}
+
+	if read != len(sets) {
+		return fmt.Errorf("expected %d result sets, got %d", len(sets), read)
+	}
+
+	return nil
+}
+
+// eachResultSet calls fn for every result set of rows.
+// The function set with [WithOnComplete] is called once with the total number of rows
+func eachResultSet(ctx context.Context, rows Rows, fn func(context.Context, int, Rows) (int, error)) (err error) {
+	var n int
+	defer func() { onComplete(ctx)(n, err) }()
+
+	multi, ok := rows.(MultiResultRows)
+	if !ok {
+		return ErrMultipleResultSetsNotSupported
+	}
+
+	// the result sets report to the outer function
+	setCtx := withoutOnComplete(ctx)
+
+	for i := 0; ; i++ {
+		count, err := fn(setCtx, i, multi)
+		n += count
+		if err != nil {
+			return err
+		}
+
+		if !multi.NextResultSet() {
+			return multi.Err()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package scan
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	matchFirstCapRe = regexp.MustCompile("(.)([A-Z][a-z]+)")
+	matchAllCapRe   = regexp.MustCompile("([a-z0-9])([A-Z])")
+)
+
+// The following functions can be used with [WithFieldNameMapper] to map
+// the names of struct fields to column names.
+// An acronym is treated as a single word, so "UserID" has the words "user" and "id"
+
+// SnakeCase maps a field name to snake_case, e.g. "UserID" to "user_id".
+// This is the default field name mapper
+func SnakeCase(str string) string {
+	snake := matchFirstCapRe.ReplaceAllString(str, "${1}_${2}")
+	snake = matchAllCapRe.ReplaceAllString(snake, "${1}_${2}")
+	return strings.ToLower(snake)
+}
+
+// SmartSnakeCase maps a field name to snake_case like [SnakeCase] but also handles
+// plural acronyms and non-ASCII letters. A new word starts at an upper case letter after a
+// lower case letter or digit, and at the last upper case letter of an acronym if it is
+// followed by a lower case letter. Digits are kept with the word before them, and if the
+// lower case letters after an acronym are followed by digits, they are one word with it.
+//
+//	"HTTPStatus" -> "http_status"
+//	"UserIDs" -> "user_ids"
+//	"OAuth2Token" -> "oauth2_token"
+//	"IPv4Address" -> "ipv4_address"
+//
+// It is not the default so that existing column names do not change
+func SmartSnakeCase(str string) string {
+	runes := []rune(str)
+
+	var b strings.Builder
+	b.Grow(len(str) + 4)
+
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && newWordAt(runes, i) {
+			b.WriteByte('_')
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// newWordAt reports if the upper case letter at i starts a new word
+func newWordAt(runes []rune, i int) bool {
+	prev := runes[i-1]
+	if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+		return true
+	}
+
+	if !unicode.IsUpper(prev) || i+1 >= len(runes) || !unicode.IsLower(runes[i+1]) {
+		return false
+	}
+
+	// A plural acronym such as "IDs" is a single word
+	if runes[i+1] == 's' && (i+2 == len(runes) || !unicode.IsLower(runes[i+2])) {
+		return false
+	}
+
+	// So is an acronym with lower case letters and digits such as "OAuth2"
+	j := i + 1
+	for j < len(runes) && unicode.IsLower(runes[j]) {
+		j++
+	}
+
+	return j == len(runes) || !unicode.IsDigit(runes[j])
+}
+
+// KebabCase maps a field name to kebab-case, e.g. "UserID" to "user-id"
+func KebabCase(str string) string {
+	return strings.ReplaceAll(SnakeCase(str), "_", "-")
+}
+
+// CamelCase maps a field name to camelCase, e.g. "UserID" to "userId"
+func CamelCase(str string) string {
+	words := strings.Split(SnakeCase(str), "_")
+	for i := 1; i < len(words); i++ {
+		words[i] = upperFirst(words[i])
+	}
+
+	return strings.Join(words, "")
+}
+
+// PascalCase maps a field name to PascalCase, e.g. "UserID" to "UserId"
+func PascalCase(str string) string {
+	return upperFirst(CamelCase(str))
+}
+
+// Identity uses the field name as the column name without any change
+func Identity(str string) string {
+	return str
+}
+
+// upperFirst changes the first letter of a word to upper case
+func upperFirst(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	if size == 0 {
+		return word
+	}
+
+	return string(unicode.ToUpper(r)) + word[size:]
+}
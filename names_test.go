@@ -0,0 +1,58 @@
+package scan
+
+import "testing"
+
+func TestNameMappers(t *testing.T) {
+	cases := []struct {
+		name                        string
+		snake, kebab, camel, pascal string
+	}{
+		{name: "ID", snake: "id", kebab: "id", camel: "id", pascal: "Id"},
+		{name: "UserID", snake: "user_id", kebab: "user-id", camel: "userId", pascal: "UserId"},
+		{name: "CreatedAt", snake: "created_at", kebab: "created-at", camel: "createdAt", pascal: "CreatedAt"},
+		{name: "HTTPStatus", snake: "http_status", kebab: "http-status", camel: "httpStatus", pascal: "HttpStatus"},
+		{name: "name", snake: "name", kebab: "name", camel: "name", pascal: "Name"},
+		{name: "", snake: "", kebab: "", camel: "", pascal: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for mapper, got := range map[string][2]string{
+				"snake":    {tc.snake, SnakeCase(tc.name)},
+				"kebab":    {tc.kebab, KebabCase(tc.name)},
+				"camel":    {tc.camel, CamelCase(tc.name)},
+				"pascal":   {tc.pascal, PascalCase(tc.name)},
+				"identity": {tc.name, Identity(tc.name)},
+			} {
+				if got[0] != got[1] {
+					t.Fatalf("%s: expected %q, got %q", mapper, got[0], got[1])
+				}
+			}
+		})
+	}
+}
+
+func TestSmartSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"ID":          "id",
+		"UserID":      "user_id",
+		"userID2":     "user_id2",
+		"UserIDs":     "user_ids",
+		"UserIDsAt":   "user_ids_at",
+		"HTTPStatus":  "http_status",
+		"HTTP2Server": "http2_server",
+		"Oauth2Token": "oauth2_token",
+		"OAuth2Token": "oauth2_token",
+		"IPv4Address": "ipv4_address",
+		"X509Cert":    "x509_cert",
+		"User_ID":     "user_id",
+		"ÜberName":    "über_name",
+		"":            "",
+	}
+
+	for name, expected := range cases {
+		if got := SmartSnakeCase(name); got != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, got)
+		}
+	}
+}
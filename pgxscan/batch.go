@@ -0,0 +1,99 @@
+package pgxscan
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stephenafamo/scan"
+)
+
+// A BatchSender can send a [pgx.Batch], such as *pgx.Conn, *pgxpool.Pool or pgx.Tx
+type BatchSender interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// Batch queues several queries to be sent together with [Batch.Exec]
+// so that they only need a single round trip.
+// Use [Queue] to add a query with the mapper for its results
+//
+//	b := &pgxscan.Batch{}
+//	users := pgxscan.Queue(b, scan.StructMapper[User](), "SELECT * FROM users")
+//	count := pgxscan.Queue(b, scan.SingleColumnMapper[int], "SELECT count(*) FROM posts")
+//
+//	if err := b.Exec(ctx, db); err != nil {
+//	    return err
+//	}
+//
+//	allUsers, _ := users.All()
+type Batch struct {
+	batch   pgx.Batch
+	readers []func(context.Context, pgx.BatchResults) error
+}
+
+// Len returns the number of queued queries
+func (b *Batch) Len() int {
+	return b.batch.Len()
+}
+
+// BatchResult holds the mapped rows of a query in a [Batch].
+// It is only populated after [Batch.Exec] has been called
+type BatchResult[T any] struct {
+	rows []T
+	err  error
+	done bool
+}
+
+// All returns the mapped rows of the query and the error from running it
+func (r *BatchResult[T]) All() ([]T, error) {
+	if !r.done {
+		return nil, errors.New("batch has not been executed")
+	}
+
+	return r.rows, r.err
+}
+
+// Queue adds the query to the batch. The results are mapped with m and can be
+// retrieved from the returned [BatchResult] after the batch has been executed
+func Queue[T any](b *Batch, m scan.Mapper[T], query string, args ...any) *BatchResult[T] {
+	result := &BatchResult[T]{}
+
+	b.batch.Queue(query, args...)
+	b.readers = append(b.readers, func(ctx context.Context, br pgx.BatchResults) error {
+		result.done = true
+
+		r, err := br.Query()
+		if err != nil {
+			result.err = err
+			return err
+		}
+
+		wrapped := adaptRows(r)
+		defer wrapped.Close()
+
+		result.rows, result.err = scan.AllFromRows(ctx, m, wrapped)
+		return result.err
+	})
+
+	return result
+}
+
+// Exec sends all the queued queries and maps the results of each one in order.
+// It returns the first error, the error of each query is also available
+// from its [BatchResult]. The results of all the queries are read even if one fails
+func (b *Batch) Exec(ctx context.Context, exec BatchSender) error {
+	br := exec.SendBatch(ctx, &b.batch)
+
+	var firstErr error
+	for _, read := range b.readers {
+		if err := read(ctx, br); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if err := br.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	return firstErr
+}
@@ -0,0 +1,25 @@
+package pgxscan
+
+import "github.com/stephenafamo/scan"
+
+// WithCompositeTypes maps fields of the given struct types to a single column
+// instead of a column for each of their fields. pgx can then scan a PostgreSQL
+// composite type into the struct, matching the attributes of the composite type
+// to the exported fields of the struct in order.
+// Pass a value of the type, pointers are dereferenced.
+//
+// The composite type must be registered with the type map of the connection
+// so that pgx knows how to decode it, for example:
+//
+//	t, err := conn.LoadType(ctx, "address")
+//	if err != nil {
+//	    return err
+//	}
+//	conn.TypeMap().RegisterType(t)
+//
+//	src, err := scan.NewStructMapperSource(pgxscan.WithCompositeTypes(Address{}))
+//	users, err := pgxscan.All(ctx, conn, scan.CustomStructMapper[User](src),
+//	    "SELECT id, address FROM users")
+func WithCompositeTypes(types ...any) scan.MappingSourceOption {
+	return scan.WithScannableConcreteTypes(types...)
+}
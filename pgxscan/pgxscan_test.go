@@ -0,0 +1,276 @@
+package pgxscan
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stephenafamo/scan"
+)
+
+// fakeRows implements pgx.Rows with values held in memory
+type fakeRows struct {
+	cols []string
+	rows [][]any
+	pos  int
+}
+
+func (r *fakeRows) Close()                        {}
+func (r *fakeRows) Err() error                    { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag { return pgconn.CommandTag{} }
+func (r *fakeRows) Conn() *pgx.Conn               { return nil }
+func (r *fakeRows) RawValues() [][]byte           { return nil }
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription {
+	fields := make([]pgconn.FieldDescription, len(r.cols))
+	for i, col := range r.cols {
+		fields[i].Name = col
+	}
+	return fields
+}
+
+func (r *fakeRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.rows)
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return r.rows[r.pos-1], nil
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	row := r.rows[r.pos-1]
+	if len(dest) != len(row) {
+		return errors.New("wrong number of destinations")
+	}
+
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(row[i]))
+	}
+	return nil
+}
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func TestRowToFunc(t *testing.T) {
+	rows := &fakeRows{
+		cols: []string{"id", "name"},
+		rows: [][]any{{1, "foo"}, {2, "bar"}},
+	}
+
+	users, err := pgx.CollectRows(rows, RowToFunc(scan.StructMapper[user]()))
+	if err != nil {
+		t.Fatalf("error collecting rows: %v", err)
+	}
+
+	if diff := cmp.Diff([]user{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}}, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	rows = &fakeRows{
+		cols: []string{"id"},
+		rows: [][]any{{1}},
+	}
+
+	_, err = pgx.CollectRows(rows, RowToFunc(scan.ColumnMapper[int]("missing")))
+	if err == nil {
+		t.Fatal("expected an error for a missing column")
+	}
+}
+
+// fakeBatch returns the results in order for the queued queries
+type fakeBatch struct {
+	results []*fakeRows
+	queued  int
+}
+
+func (f *fakeBatch) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	f.queued = b.Len()
+	return &fakeBatchResults{results: f.results}
+}
+
+type fakeBatchResults struct {
+	results []*fakeRows
+	pos     int
+}
+
+func (r *fakeBatchResults) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, nil }
+func (r *fakeBatchResults) QueryRow() pgx.Row                { return nil }
+func (r *fakeBatchResults) Close() error                     { return nil }
+
+func (r *fakeBatchResults) Query() (pgx.Rows, error) {
+	if r.pos >= len(r.results) {
+		return nil, errors.New("no more results")
+	}
+
+	r.pos++
+	return r.results[r.pos-1], nil
+}
+
+func TestBatch(t *testing.T) {
+	b := &Batch{}
+	users := Queue(b, scan.StructMapper[user](), "SELECT id, name FROM users")
+	ids := Queue(b, scan.SingleColumnMapper[int], "SELECT id FROM users WHERE id > $1", 1)
+
+	if _, err := users.All(); err == nil {
+		t.Fatal("expected an error before the batch is executed")
+	}
+
+	sender := &fakeBatch{results: []*fakeRows{
+		{cols: []string{"id", "name"}, rows: [][]any{{1, "foo"}, {2, "bar"}}},
+		{cols: []string{"id"}, rows: [][]any{{2}}},
+	}}
+
+	if err := b.Exec(context.Background(), sender); err != nil {
+		t.Fatalf("error executing batch: %v", err)
+	}
+
+	if sender.queued != 2 {
+		t.Fatalf("expected 2 queued queries, got %d", sender.queued)
+	}
+
+	allUsers, err := users.All()
+	if err != nil {
+		t.Fatalf("error getting users: %v", err)
+	}
+
+	if diff := cmp.Diff([]user{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}}, allUsers); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	allIDs, err := ids.All()
+	if err != nil {
+		t.Fatalf("error getting ids: %v", err)
+	}
+
+	if diff := cmp.Diff([]int{2}, allIDs); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+// typedRows decodes the raw values with a pgx type map like a connection would
+type typedRows struct {
+	*fakeRows
+	m    *pgtype.Map
+	oids []uint32
+}
+
+func (r *typedRows) Scan(dest ...any) error {
+	row := r.rows[r.pos-1]
+	for i, d := range dest {
+		if err := r.m.Scan(r.oids[i], pgtype.BinaryFormatCode, row[i].([]byte), d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type address struct {
+	Street string
+	City   string
+}
+
+type userWithAddress struct {
+	ID      int32
+	Address address
+}
+
+func TestCompositeTypes(t *testing.T) {
+	const addressOID = 100000
+
+	m := pgtype.NewMap()
+	textType, _ := m.TypeForName("text")
+	m.RegisterType(&pgtype.Type{Name: "address", OID: addressOID, Codec: &pgtype.CompositeCodec{
+		Fields: []pgtype.CompositeCodecField{
+			{Name: "street", Type: textType},
+			{Name: "city", Type: textType},
+		},
+	}})
+
+	id, err := m.Encode(pgtype.Int4OID, pgtype.BinaryFormatCode, int32(1), nil)
+	if err != nil {
+		t.Fatalf("error encoding id: %v", err)
+	}
+
+	addr, err := m.Encode(addressOID, pgtype.BinaryFormatCode, address{Street: "Main St", City: "Lagos"}, nil)
+	if err != nil {
+		t.Fatalf("error encoding address: %v", err)
+	}
+
+	src, err := scan.NewStructMapperSource(WithCompositeTypes(address{}))
+	if err != nil {
+		t.Fatalf("couldn't get mapper source: %v", err)
+	}
+
+	rows := &typedRows{
+		fakeRows: &fakeRows{cols: []string{"id", "address"}, rows: [][]any{{id, addr}}},
+		m:        m,
+		oids:     []uint32{pgtype.Int4OID, addressOID},
+	}
+
+	users, err := scan.AllFromRows(context.Background(), scan.CustomStructMapper[userWithAddress](src), adaptRows(rows))
+	if err != nil {
+		t.Fatalf("error scanning rows: %v", err)
+	}
+
+	expected := []userWithAddress{{ID: 1, Address: address{Street: "Main St", City: "Lagos"}}}
+	if diff := cmp.Diff(expected, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+}
+
+// closingRows fails to scan the given row and closes the rows like pgx does
+type closingRows struct {
+	*fakeRows
+	failAt int
+	err    error
+}
+
+func (r *closingRows) Next() bool {
+	return r.err == nil && r.fakeRows.Next()
+}
+
+func (r *closingRows) Err() error {
+	return r.err
+}
+
+func (r *closingRows) Scan(dest ...any) error {
+	if r.pos == r.failAt {
+		r.err = errors.New("can't scan row")
+		return r.err
+	}
+	return r.fakeRows.Scan(dest...)
+}
+
+func TestAllTolerantScanError(t *testing.T) {
+	rows := &closingRows{
+		fakeRows: &fakeRows{
+			cols: []string{"id", "name"},
+			rows: [][]any{{1, "foo"}, {2, "bar"}, {3, "baz"}},
+		},
+		failAt: 2,
+	}
+
+	users, rowErrs, err := scan.AllTolerantFromRows(context.Background(), scan.StructMapper[user](), adaptRows(rows))
+	if diff := cmp.Diff([]user{{ID: 1, Name: "foo"}}, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if len(rowErrs) != 1 {
+		t.Fatalf("expected 1 row error, got %d: %v", len(rowErrs), rowErrs)
+	}
+
+	// pgx closes the rows after a failed scan, so the rows after it are not scanned
+	// and the scan error is also returned for the whole query
+	if !errors.Is(err, rows.err) {
+		t.Fatalf("expected the scan error for the query, got %v", err)
+	}
+}
@@ -1,39 +1,72 @@
 package scan
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 var zeroValue reflect.Value
 
-func wrapRows(r Rows, allowUnknown bool) (*Row, error) {
+func wrapRows(ctx context.Context, r Rows) (*Row, error) {
 	cols, err := r.Columns()
 	if err != nil {
 		return nil, err
 	}
 
+	v := newRow(ctx, cols)
+	v.r = r
+
+	return v, nil
+}
+
+// newRow creates a row with the given columns and the settings from the context
+func newRow(ctx context.Context, cols []string) *Row {
+	allowUnknown, _ := ctx.Value(CtxKeyAllowUnknownColumns).(bool)
+	diagnose, _ := ctx.Value(CtxKeyDiagnoseScanErrors).(bool)
+	record, _ := ctx.Value(CtxKeyRecordScheduledColumns).(bool)
+
 	return &Row{
-		r:                r,
 		columns:          cols,
 		scanDestinations: make([]reflect.Value, len(cols)),
 		allowUnknown:     allowUnknown,
-	}, nil
+		diagnose:         diagnose,
+		record:           record,
+	}
 }
 
 // Row represents a single row from the query and is passed to the [BeforeFunc]
 // when sent to a mapper's before function, scans should be scheduled
-// with either the [ScheduleScan] or [ScheduleScanx] methods
+// with the [ScheduleScan], [ScheduleScanx], [ScheduleScanAll], [ScheduleScanByNames], [ScheduleScanByIndex] or [ScheduleScanConvert] methods
 type Row struct {
 	r                   Rows
 	columns             []string
 	scanDestinations    []reflect.Value
+	scanConverters      []func(reflect.Value) error
 	unknownDestinations []string
 	allowUnknown        bool
+	diagnose            bool
+
+	// set with [WithWarnUnknownColumns]
+	unknownWarner func(cols []string)
+	unknownWarned bool
+
+	// set with [WithScheduleWarner], scans for missing columns are skipped
+	scheduleWarner func(col string)
+
+	// set when recording the scheduled columns with [CtxKeyRecordScheduledColumns]
+	record      bool
+	scheduled   []string
+	unscheduled []string
+
+	// the targets of the current row once it is scanned, see [Row.Scanned]
+	scanned []any
 }
 
 // ScheduleScan schedules a scan for the column name into the given value
-// val should be a pointer
+// val should be a pointer.
+// If multiple columns have the same name, only the first one is scanned into val
 func (r *Row) ScheduleScan(colName string, val any) {
 	r.ScheduleScanx(colName, reflect.ValueOf(val))
 }
@@ -41,14 +74,165 @@ func (r *Row) ScheduleScan(colName string, val any) {
 // ScheduleScanx schedules a scan for the column name into the given reflect.Value
 // val.Kind() should be reflect.Pointer
 func (r *Row) ScheduleScanx(colName string, val reflect.Value) {
+	r.scheduleScan(colName, val, nil, false)
+}
+
+// ScheduleScanAll works like [Row.ScheduleScan] but if multiple columns have the same name,
+// all of them are scanned into val. Since the columns are scanned in order,
+// val holds the value of the last matching column.
+// This is useful for joins where the same column name appears more than once
+func (r *Row) ScheduleScanAll(colName string, val any) {
+	r.ScheduleScanAllx(colName, reflect.ValueOf(val))
+}
+
+// ScheduleScanAllx works like [Row.ScheduleScanx] but schedules the scan for every
+// column with the name. See [Row.ScheduleScanAll]
+func (r *Row) ScheduleScanAllx(colName string, val reflect.Value) {
+	r.scheduleScan(colName, val, nil, true)
+}
+
+// ScheduleScanByNames schedules a scan into the given value for the first of the column names
+// that is in the result. This is useful to support more than one version of a schema,
+// for example when a column has been renamed.
+// If none of the columns are in the result, it is recorded as an unknown destination.
+// val should be a pointer
+func (r *Row) ScheduleScanByNames(colNames []string, val any) {
+	r.ScheduleScanByNamesx(colNames, reflect.ValueOf(val))
+}
+
+// ScheduleScanByNamesx works like [Row.ScheduleScanByNames] with a reflect.Value.
+// val.Kind() should be reflect.Pointer
+func (r *Row) ScheduleScanByNamesx(colNames []string, val reflect.Value) {
+	for _, colName := range colNames {
+		if r.HasColumn(colName) {
+			r.scheduleScan(colName, val, nil, false)
+			return
+		}
+	}
+
+	r.unknownDestinations = append(r.unknownDestinations, strings.Join(colNames, "|"))
+}
+
+// ScheduleScanByIndex schedules a scan for the column at the given position into the given value.
+// This is useful when the column names are not known or not unique.
+// val should be a pointer
+func (r *Row) ScheduleScanByIndex(index int, val any) {
+	if index < 0 || index >= len(r.columns) {
+		r.unknownDestinations = append(r.unknownDestinations, fmt.Sprintf("#%d", index))
+		return
+	}
+
+	r.scanDestinations[index] = reflect.ValueOf(val)
+	if r.scanConverters != nil {
+		r.scanConverters[index] = nil
+	}
+}
+
+// ScheduleScanConvert schedules a scan for the column name into the given reflect.Value
+// and calls convert with it once the row has been scanned.
+// val.Kind() should be reflect.Pointer
+//
+// The conversions run in the order of the columns in the result, not the order in
+// which they were scheduled, and all of them run before the after function of the mapper.
+// Scheduling another scan for the same column replaces the conversion.
+// If a conversion returns an error, the remaining conversions are skipped
+func (r *Row) ScheduleScanConvert(colName string, val reflect.Value, convert func(reflect.Value) error) {
+	r.scheduleScan(colName, val, convert, false)
+}
+
+func (r *Row) scheduleScan(colName string, val reflect.Value, convert func(reflect.Value) error, all bool) {
+	var found bool
 	for i, n := range r.columns {
-		if n == colName {
-			r.scanDestinations[i] = val
+		if n != colName {
+			continue
+		}
+
+		found = true
+		r.scanDestinations[i] = val
+
+		if convert != nil && r.scanConverters == nil {
+			r.scanConverters = make([]func(reflect.Value) error, len(r.columns))
+		}
+		if r.scanConverters != nil {
+			r.scanConverters[i] = convert
+		}
+
+		if !all {
 			return
 		}
 	}
 
-	r.unknownDestinations = append(r.unknownDestinations, colName)
+	if !found {
+		r.unknownDestinations = append(r.unknownDestinations, colName)
+	}
+}
+
+// HasColumn reports whether the result has a column with the given name.
+// This is useful to schedule scans conditionally in a [BeforeFunc]
+func (r *Row) HasColumn(name string) bool {
+	return r.ColumnIndex(name) >= 0
+}
+
+// ColumnIndex returns the position of the first column with the given name
+// or -1 if the result has no such column.
+// The index can be used with [Row.ScheduleScanByIndex]
+func (r *Row) ColumnIndex(name string) int {
+	for i, n := range r.columns {
+		if n == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Scanned returns a copy of the scanned values of the current row in the order of the columns.
+// This is useful for positional processing of the whole row in the after function
+// of a mapper that passes the *Row in the link from its before function.
+//
+// Each value is the scan destination with the pointer removed, and columns that are
+// discarded as unknown are included. It is only valid after the row is scanned:
+// it returns nil before that, and is reset before the next row
+func (r *Row) Scanned() []any {
+	if r.scanned == nil {
+		return nil
+	}
+
+	vals := make([]any, len(r.scanned))
+	for i, target := range r.scanned {
+		vals[i] = reflect.ValueOf(target).Elem().Interface()
+	}
+
+	return vals
+}
+
+// ScheduledColumns returns the names of the columns that had a scheduled scan
+// when the last row was scanned.
+// It is only recorded if [CtxKeyRecordScheduledColumns] is set in the context
+func (r *Row) ScheduledColumns() []string {
+	return r.scheduled
+}
+
+// UnscheduledColumns returns the names of the columns that had no scheduled scan
+// when the last row was scanned. These are only possible when unknown columns are allowed
+// and their values are discarded.
+// It is only recorded if [CtxKeyRecordScheduledColumns] is set in the context
+func (r *Row) UnscheduledColumns() []string {
+	return r.unscheduled
+}
+
+// recordScheduled records the columns with and without a scheduled scan
+func (r *Row) recordScheduled() {
+	r.scheduled = make([]string, 0, len(r.columns))
+	r.unscheduled = nil
+
+	for i, name := range r.columns {
+		if r.scanDestinations[i] != zeroValue {
+			r.scheduled = append(r.scheduled, name)
+		} else {
+			r.unscheduled = append(r.unscheduled, name)
+		}
+	}
 }
 
 // To get a copy of the columns to pass to mapper generators
@@ -61,26 +245,130 @@ func (r *Row) columnsCopy() []string {
 }
 
 func (r *Row) scanCurrentRow() error {
-	if len(r.unknownDestinations) > 0 {
-		return createError(fmt.Errorf("unknown columns to map to: %v", r.unknownDestinations), r.unknownDestinations...)
-	}
-
 	targets, err := r.createTargets()
 	if err != nil {
 		return err
 	}
 
-	err = r.r.Scan(targets...)
+	return r.scanTargets(targets)
+}
+
+// scanTargets scans the current row into the targets from [Row.createTargets].
+// Unlike errors from createTargets, its errors depend on the values of the row
+func (r *Row) scanTargets(targets []any) error {
+	// Only set if the missing columns are skipped with [WithScheduleWarner]
+	missing := r.unknownDestinations
+	r.unknownDestinations = nil
+
+	// The destinations are scheduled again for every row, so they are
+	// reset even if the row fails and the next row does not reuse them
+	defer r.resetTargets()
+
+	err := r.r.Scan(targets...)
 	if err != nil {
+		if r.diagnose {
+			return r.diagnoseScanError(targets, err)
+		}
 		return err
 	}
 
+	if err := r.convertScanned(); err != nil {
+		return err
+	}
+
+	if r.record {
+		r.recordScheduled()
+	}
+
+	for _, col := range missing {
+		r.scheduleWarner(col)
+	}
+
+	r.scanned = targets
+	return nil
+}
+
+// resetTargets clears the destinations and conversions scheduled for the current row
+func (r *Row) resetTargets() {
 	r.scanDestinations = make([]reflect.Value, len(r.columns))
+	r.scanConverters = nil
+}
+
+// convertScanned runs the conversions scheduled with [Row.ScheduleScanConvert]
+func (r *Row) convertScanned() error {
+	if r.scanConverters == nil {
+		return nil
+	}
+
+	converters := r.scanConverters
+	r.scanConverters = nil
+
+	for i, convert := range converters {
+		if convert == nil {
+			continue
+		}
+
+		if err := convert(r.scanDestinations[i]); err != nil {
+			return createError(err, "convert", r.columns[i])
+		}
+	}
+
 	return nil
 }
 
+// ScanError is the cause of the error returned when a column fails to scan and
+// scan errors are diagnosed with [CtxKeyDiagnoseScanErrors] or [WithScanErrorDetail].
+// Use [errors.As] to get it from the returned error
+type ScanError struct {
+	// the zero-based index of the column in the result
+	Index  int
+	Column string
+	// the type of the destination
+	Type reflect.Type
+	Err  error
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("scanning column %q (type %s): %v", e.Column, e.Type, e.Err)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// diagnoseScanError scans the columns one at a time to find the one that failed
+// and adds the column to the error. The other columns are scanned into values
+// that are discarded. If no single column fails, the original error is returned
+func (r *Row) diagnoseScanError(targets []any, scanErr error) error {
+	single := make([]any, len(targets))
+
+	for i := range targets {
+		for j := range single {
+			single[j] = new(any)
+		}
+		single[i] = targets[i]
+
+		if err := r.r.Scan(single...); err != nil {
+			typ := reflect.TypeOf(targets[i])
+			if typ.Kind() == reflect.Pointer {
+				typ = typ.Elem()
+			}
+
+			err = &ScanError{Index: i, Column: r.columns[i], Type: typ, Err: err}
+			return createError(err, "scan error", r.columns[i])
+		}
+	}
+
+	return scanErr
+}
+
 func (r *Row) createTargets() ([]any, error) {
+	if len(r.unknownDestinations) > 0 && r.scheduleWarner == nil {
+		return nil, createError(fmt.Errorf("unknown columns to map to: %v", r.unknownDestinations), r.unknownDestinations...)
+	}
+
 	targets := make([]any, len(r.columns))
+	var discarded []string
 
 	for i, name := range r.columns {
 		dest := r.scanDestinations[i]
@@ -98,6 +386,12 @@ func (r *Row) createTargets() ([]any, error) {
 		// Some drivers cannot work with nil values, so valid pointers should be
 		// used for all column targets, even if they are discarded afterwards.
 		targets[i] = new(interface{})
+		discarded = append(discarded, name)
+	}
+
+	if len(discarded) > 0 && r.unknownWarner != nil && !r.unknownWarned {
+		r.unknownWarned = true
+		r.unknownWarner(discarded)
 	}
 
 	return targets, nil
@@ -0,0 +1,124 @@
+// Package scantest provides helpers to test mappers without a database
+package scantest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/stephenafamo/scan"
+)
+
+// NewMockRows returns [scan.Rows] that return the given rows held in memory.
+// This makes it possible to test mappers without a database driver
+//
+//	rows := scantest.NewMockRows([]string{"id", "name"}, [][]any{{1, "foo"}})
+//	users, err := scan.AllFromRows(ctx, scan.StructMapper[User](), rows)
+//
+// The rows are returned as *sql.Rows, so values are converted when scanning
+// in the same way as with any other driver. Scanning a value into a destination
+// of an incompatible type or NULL into a type that is not nullable returns an error.
+// The values must be valid driver values after conversion with [driver.DefaultParameterConverter],
+// and every row must have the same number of values as there are columns
+func NewMockRows(columns []string, rows [][]any) scan.Rows {
+	db := sql.OpenDB(connector{columns: columns, rows: rows})
+
+	// The rows keep their connection open, so the
+	// database can be closed before they are read
+	defer db.Close()
+
+	r, err := db.Query("")
+	if err != nil {
+		return errRows{err: err}
+	}
+
+	return r
+}
+
+// errRows is returned if the rows could not be created
+type errRows struct {
+	err error
+}
+
+func (e errRows) Scan(...any) error          { return e.err }
+func (e errRows) Columns() ([]string, error) { return nil, e.err }
+func (e errRows) Next() bool                 { return false }
+func (e errRows) Close() error               { return nil }
+func (e errRows) Err() error                 { return e.err }
+
+type connector struct {
+	columns []string
+	rows    [][]any
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return conn(c), nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return mockDriver{}
+}
+
+type mockDriver struct{}
+
+func (mockDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("scantest: use NewMockRows")
+}
+
+type conn connector
+
+func (c conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("scantest: prepared statements are not supported")
+}
+
+func (c conn) Close() error {
+	return nil
+}
+
+func (c conn) Begin() (driver.Tx, error) {
+	return nil, errors.New("scantest: transactions are not supported")
+}
+
+func (c conn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &rows{columns: c.columns, rows: c.rows}, nil
+}
+
+type rows struct {
+	columns []string
+	rows    [][]any
+	pos     int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+
+	row := r.rows[r.pos]
+	r.pos++
+
+	if len(row) != len(r.columns) {
+		return fmt.Errorf("scantest: row %d has %d values but there are %d columns", r.pos-1, len(row), len(r.columns))
+	}
+
+	for i, val := range row {
+		v, err := driver.DefaultParameterConverter.ConvertValue(val)
+		if err != nil {
+			return fmt.Errorf("scantest: row %d, column %s: %w", r.pos-1, r.columns[i], err)
+		}
+		dest[i] = v
+	}
+
+	return nil
+}
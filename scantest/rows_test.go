@@ -0,0 +1,55 @@
+package scantest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stephenafamo/scan"
+)
+
+type user struct {
+	ID   int
+	Name *string
+}
+
+func TestMockRows(t *testing.T) {
+	ctx := context.Background()
+	foo := "foo"
+
+	rows := NewMockRows([]string{"id", "name"}, [][]any{{1, "foo"}, {2, nil}})
+	users, err := scan.AllFromRows(ctx, scan.StructMapper[user](), rows)
+	if err != nil {
+		t.Fatalf("error scanning rows: %v", err)
+	}
+
+	if diff := cmp.Diff([]user{{ID: 1, Name: &foo}, {ID: 2}}, users); diff != "" {
+		t.Fatalf("diff: %s", diff)
+	}
+
+	if err := rows.Close(); err != nil {
+		t.Fatalf("error closing rows: %v", err)
+	}
+
+	rows = NewMockRows([]string{"id"}, nil)
+	if _, err := scan.OneFromRows(ctx, scan.SingleColumnMapper[int], rows); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	rows = NewMockRows([]string{"id"}, [][]any{{"not a number"}})
+	if _, err := scan.AllFromRows(ctx, scan.SingleColumnMapper[int], rows); err == nil {
+		t.Fatal("expected an error for a type mismatch")
+	}
+
+	rows = NewMockRows([]string{"id"}, [][]any{{nil}})
+	if _, err := scan.AllFromRows(ctx, scan.SingleColumnMapper[int], rows); err == nil {
+		t.Fatal("expected an error for NULL into an int")
+	}
+
+	rows = NewMockRows([]string{"id", "name"}, [][]any{{1}})
+	if _, err := scan.AllFromRows(ctx, scan.StructMapper[user](), rows); err == nil {
+		t.Fatal("expected an error for a row with missing values")
+	}
+}
@@ -1,33 +1,22 @@
 package scan
 
 import (
+	"container/list"
 	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
-	"regexp"
 	"strings"
 	"sync"
 )
 
-var (
-	matchFirstCapRe     = regexp.MustCompile("(.)([A-Z][a-z]+)")
-	matchAllCapRe       = regexp.MustCompile("([a-z0-9])([A-Z])")
-	defaultStructMapper = newDefaultMapperSourceImpl()
-)
-
-// snakeCaseFieldFunc is a NameMapperFunc that maps struct field to snake case.
-func snakeCaseFieldFunc(str string) string {
-	snake := matchFirstCapRe.ReplaceAllString(str, "${1}_${2}")
-	snake = matchAllCapRe.ReplaceAllString(snake, "${1}_${2}")
-	return strings.ToLower(snake)
-}
+var defaultStructMapper = newDefaultMapperSourceImpl()
 
 func newDefaultMapperSourceImpl() *mapperSourceImpl {
 	return &mapperSourceImpl{
 		structTagKey:    "db",
 		columnSeparator: ".",
-		fieldMapperFn:   snakeCaseFieldFunc,
+		fieldMapperFn:   SnakeCase,
 		scannableTypes:  []reflect.Type{reflect.TypeOf((*sql.Scanner)(nil)).Elem()},
 		maxDepth:        3,
 		cache:           make(map[reflect.Type]mapping),
@@ -57,7 +46,8 @@ func WithStructTagKey(tagKey string) MappingSourceOption {
 	}
 }
 
-// WithColumnSeparator allows to use a custom separator character for column name when combining nested structs.
+// WithColumnSeparator allows to use a custom separator for column name when combining nested structs.
+// The separator can be more than one character, such as "__".
 // The default separator is "." character.
 func WithColumnSeparator(separator string) MappingSourceOption {
 	return func(src *mapperSourceImpl) error {
@@ -67,7 +57,8 @@ func WithColumnSeparator(separator string) MappingSourceOption {
 }
 
 // WithFieldNameMapper allows to use a custom function to map field name to column names.
-// The default function maps fields names to "snake_case"
+// The default function maps fields names to "snake_case".
+// The function is only used for untagged fields, the struct tag always takes precedence
 func WithFieldNameMapper(mapperFn func(string) string) MappingSourceOption {
 	return func(src *mapperSourceImpl) error {
 		src.fieldMapperFn = mapperFn
@@ -75,6 +66,30 @@ func WithFieldNameMapper(mapperFn func(string) string) MappingSourceOption {
 	}
 }
 
+// WithFieldNameMapperPath works like [WithFieldNameMapper] but the function receives
+// the names of the fields from the root struct to the current field, including embedded structs.
+// This allows the column names to depend on how deeply the field is nested.
+// The returned name is still joined with the names of the parent fields using the column separator.
+// If set, it is used instead of the function set with [WithFieldNameMapper]
+func WithFieldNameMapperPath(mapperFn func(path []string) string) MappingSourceOption {
+	return func(src *mapperSourceImpl) error {
+		src.fieldPathMapperFn = mapperFn
+		return nil
+	}
+}
+
+// WithColumnTransformer allows to use a function to normalize the column names
+// returned by the query before they are matched to the fields,
+// for example to trim quotes or lowercase them.
+// This is the inverse of [WithFieldNameMapper] which transforms the field names.
+// When a struct tag prefix is used, it is matched against the transformed column name
+func WithColumnTransformer(transformFn func(string) string) MappingSourceOption {
+	return func(src *mapperSourceImpl) error {
+		src.columnTransformFn = transformFn
+		return nil
+	}
+}
+
 // WithScannableTypes specifies a list of interfaces that underlying database library can scan into.
 // In case the destination type passed to scan implements one of those interfaces,
 // scan will handle it as primitive type case i.e. simply pass the destination to the database library.
@@ -111,36 +126,262 @@ func WithScannableTypes(scannableTypes ...any) MappingSourceOption {
 	}
 }
 
+// WithScannableConcreteTypes specifies a list of concrete types that the underlying
+// database library can scan into. Like [WithScannableTypes], fields of these types are
+// treated as single values, but the type must match exactly instead of implementing an interface.
+// This is useful for types where detecting the interface is not reliable.
+// Pass a value of the type, pointers are dereferenced.
+//
+//	scan.WithScannableConcreteTypes(decimal.Decimal{})
+func WithScannableConcreteTypes(types ...any) MappingSourceOption {
+	return func(src *mapperSourceImpl) error {
+		for _, t := range types {
+			typ := reflect.TypeOf(t)
+			if typ == nil {
+				return fmt.Errorf("scannable concrete type must not be nil")
+			}
+
+			if typ.Kind() == reflect.Pointer {
+				typ = typ.Elem()
+			}
+
+			if typ.Kind() == reflect.Interface {
+				return fmt.Errorf("scannable concrete type must not be an interface, got %s. Use WithScannableTypes instead", typ)
+			}
+
+			src.scannableConcrete = append(src.scannableConcrete, typ)
+		}
+		return nil
+	}
+}
+
+// WithScannableFallback also maps the fields of struct types that implement one
+// of the scannable types. When the column for the whole struct is selected, it is
+// scanned as a single value and the columns of its fields are treated as unknown columns.
+// When it is not selected, the struct is mapped field by field.
+//
+// Without this option, scannable structs are only ever scanned as a single value
+func WithScannableFallback() MappingSourceOption {
+	return func(src *mapperSourceImpl) error {
+		src.scannableFallback = true
+		return nil
+	}
+}
+
+// WithUnexportedFields makes the mappers also set unexported fields that have a struct tag.
+// Unexported fields without a tag are still skipped.
+//
+// WARNING: This uses the unsafe package to bypass the restrictions on setting unexported
+// fields. Only use it for types that you own, since it can break the invariants
+// that a type maintains for its unexported fields
+func WithUnexportedFields() MappingSourceOption {
+	return func(src *mapperSourceImpl) error {
+		src.unexported = true
+		return nil
+	}
+}
+
+// WithStrictMapping makes the mappers return an error if multiple fields
+// of a struct map to the same column name. By default, only the first field is used
+func WithStrictMapping() MappingSourceOption {
+	return func(src *mapperSourceImpl) error {
+		src.strict = true
+		return nil
+	}
+}
+
+// WithCacheSize limits the number of struct mappings kept in the cache.
+// When the limit is reached, the least recently used mapping is removed.
+// The default of 0 means the cache is unbounded
+func WithCacheSize(size int) MappingSourceOption {
+	return func(src *mapperSourceImpl) error {
+		if size < 0 {
+			return fmt.Errorf("cache size must not be negative, got %d", size)
+		}
+
+		src.cacheSize = size
+		return nil
+	}
+}
+
+// WithSourceInterfaceFactory registers a constructor for fields of the given interface type
+// for every mapper created from the source.
+// In order for reflection to capture the interface type, you must pass it by pointer.
+//
+// The constructor should return a pointer to a concrete value which is scanned into
+// and then set in the field. If the pointer itself does not implement the interface,
+// the value it points to is used instead.
+//
+//	scan.WithSourceInterfaceFactory((*Payload)(nil), func() any { return new(JSONPayload) })
+func WithSourceInterfaceFactory(iface any, factory func() any) MappingSourceOption {
+	return func(src *mapperSourceImpl) error {
+		it := reflect.TypeOf(iface)
+		if it == nil || it.Kind() != reflect.Pointer || it.Elem().Kind() != reflect.Interface {
+			return fmt.Errorf("interface type must be a pointer to an interface, got %T", iface)
+		}
+
+		if factory == nil {
+			return fmt.Errorf("interface factory for %s is nil", it.Elem())
+		}
+
+		if src.factories == nil {
+			src.factories = make(map[reflect.Type]func() reflect.Value)
+		}
+
+		src.factories[it.Elem()] = func() reflect.Value {
+			return reflect.ValueOf(factory())
+		}
+		return nil
+	}
+}
+
 // mapperSourceImpl is an implementation of StructMapperSource.
 type mapperSourceImpl struct {
 	structTagKey    string
 	columnSeparator string
 	fieldMapperFn   func(string) string
-	scannableTypes  []reflect.Type
-	maxDepth        int
-	cache           map[reflect.Type]mapping
-	mutex           sync.RWMutex
+	// receives the path of field names, used instead of fieldMapperFn if set
+	fieldPathMapperFn func(path []string) string
+	// normalizes the column names from the query
+	columnTransformFn func(string) string
+	scannableTypes    []reflect.Type
+	scannableConcrete []reflect.Type
+	// also map the fields of scannable structs
+	scannableFallback bool
+	// return an error for duplicate columns
+	strict bool
+	// map unexported fields that have a struct tag
+	unexported bool
+	factories  map[reflect.Type]func() reflect.Value
+	maxDepth   int
+	cache      map[reflect.Type]mapping
+	mutex      sync.RWMutex
+
+	// used to evict the least recently used mappings if cacheSize is set
+	cacheSize  int
+	cacheOrder *list.List
+	cacheElems map[reflect.Type]*list.Element
+}
+
+func (s *mapperSourceImpl) isScannableConcrete(typ reflect.Type) bool {
+	for _, concrete := range s.scannableConcrete {
+		if typ == concrete {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *mapperSourceImpl) interfaceFactories() map[reflect.Type]func() reflect.Value {
+	return s.factories
+}
+
+func (s *mapperSourceImpl) columnTransformer() func(string) string {
+	return s.columnTransformFn
+}
+
+// ClearCache removes all the cached struct mappings
+func (s *mapperSourceImpl) ClearCache() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.cache = make(map[reflect.Type]mapping)
+	s.cacheOrder = nil
+	s.cacheElems = nil
 }
 
 func (s *mapperSourceImpl) getMapping(typ reflect.Type) (mapping, error) {
-	s.mutex.RLock()
-	m, ok := s.cache[typ]
-	s.mutex.RUnlock()
+	if m, ok := s.cached(typ); ok {
+		return m, nil
+	}
+
+	// The fields are appended depth first in declaration order,
+	// which keeps the order of the mapping stable
+	var m mapping
+	s.setMappings(typ, "", nil, nil, make(visited), &m, nil)
 
+	if s.strict {
+		if err := checkDuplicates(typ, m); err != nil {
+			return nil, err
+		}
+	}
+
+	s.store(typ, m)
+
+	return m, nil
+}
+
+// checkDuplicates returns an error if multiple fields map to the same column
+func checkDuplicates(typ reflect.Type, m mapping) error {
+	for i, info := range m {
+		positions := [][]int{info.position}
+		for _, other := range m[i+1:] {
+			if other.name == info.name {
+				positions = append(positions, other.position)
+			}
+		}
+
+		if len(positions) > 1 {
+			err := fmt.Errorf("fields %v of %s all map to the column %q", positions, typ, info.name)
+			return createError(err, "duplicate column", info.name)
+		}
+	}
+
+	return nil
+}
+
+func (s *mapperSourceImpl) cached(typ reflect.Type) (mapping, bool) {
+	if s.cacheSize == 0 {
+		s.mutex.RLock()
+		defer s.mutex.RUnlock()
+
+		m, ok := s.cache[typ]
+		return m, ok
+	}
+
+	// Reads also update the order, so a write lock is needed
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.cache[typ]
 	if ok {
-		return m, nil
+		s.cacheOrder.MoveToFront(s.cacheElems[typ])
 	}
 
-	s.setMappings(typ, "", make(visited), &m, nil)
+	return m, ok
+}
 
+func (s *mapperSourceImpl) store(typ reflect.Type, m mapping) {
 	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	s.cache[typ] = m
-	s.mutex.Unlock()
+	if s.cacheSize == 0 {
+		return
+	}
 
-	return m, nil
+	if s.cacheOrder == nil {
+		s.cacheOrder = list.New()
+		s.cacheElems = make(map[reflect.Type]*list.Element)
+	}
+
+	if elem, ok := s.cacheElems[typ]; ok {
+		s.cacheOrder.MoveToFront(elem)
+		return
+	}
+
+	s.cacheElems[typ] = s.cacheOrder.PushFront(typ)
+
+	for s.cacheOrder.Len() > s.cacheSize {
+		oldest := s.cacheOrder.Back()
+		evicted := s.cacheOrder.Remove(oldest).(reflect.Type)
+		delete(s.cache, evicted)
+		delete(s.cacheElems, evicted)
+	}
 }
 
-func (s *mapperSourceImpl) setMappings(typ reflect.Type, prefix string, v visited, m *mapping, inits [][]int, position ...int) {
+func (s *mapperSourceImpl) setMappings(typ reflect.Type, prefix string, path, fieldNames []string, v visited, m *mapping, inits [][]int, position ...int) {
 	count := v[typ]
 	if count > s.maxDepth {
 		return
@@ -150,23 +391,45 @@ func (s *mapperSourceImpl) setMappings(typ reflect.Type, prefix string, v visite
 	var hasExported bool
 
 	var isPointer bool
-	if typ.Kind() == reflect.Pointer {
+	for typ.Kind() == reflect.Pointer {
 		isPointer = true
 		typ = typ.Elem()
 	}
 
 	// If it implements a scannable type, then it can be used
-	// as a value itself. Return it
+	// as a value itself. Return it unless its fields should also be
+	// mapped as a fallback
+	isScannable := s.isScannableConcrete(typ)
 	for _, scannable := range s.scannableTypes {
-		if reflect.PtrTo(typ).Implements(scannable) {
-			*m = append(*m, mapinfo{
-				name:      prefix,
-				position:  position,
-				init:      inits,
-				isPointer: isPointer,
-			})
+		if isScannable {
+			break
+		}
+		isScannable = reflect.PtrTo(typ).Implements(scannable)
+	}
+
+	if isScannable {
+		*m = append(*m, mapinfo{
+			name:      prefix,
+			path:      path,
+			position:  position,
+			init:      inits,
+			isPointer: isPointer,
+		})
+	}
+
+	if isScannable {
+		if !s.scannableFallback || prefix == "" || typ.Kind() != reflect.Struct {
 			return
 		}
+
+		// The fields are only used if the column for the whole struct is not selected
+		start := len(*m)
+		defer func() {
+			for i := start; i < len(*m); i++ {
+				info := &(*m)[i]
+				info.fallbackOf = append(info.fallbackOf[:len(info.fallbackOf):len(info.fallbackOf)], position)
+			}
+		}()
 	}
 
 	// Go through the struct fields and populate the map.
@@ -174,20 +437,29 @@ func (s *mapperSourceImpl) setMappings(typ reflect.Type, prefix string, v visite
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
 
-		// Don't consider unexported fields
-		if !field.IsExported() {
+		// Don't consider unexported fields unless they are
+		// tagged and allowed with WithUnexportedFields
+		if !field.IsExported() && (!s.unexported || field.Tag.Get(s.structTagKey) == "") {
 			continue
 		}
 
 		// Skip columns that have the tag "-"
-		tag := strings.Split(field.Tag.Get(s.structTagKey), ",")[0]
+		tagOpts := strings.Split(field.Tag.Get(s.structTagKey), ",")
+		tag := tagOpts[0]
 		if tag == "-" {
 			continue
 		}
 
 		hasExported = true
 
+		// Fields with the readonly tag option are never scanned into
+		if hasTagOption(tagOpts[1:], "readonly") {
+			continue
+		}
+
 		key := prefix
+		keyPath := path
+		currentNames := append(fieldNames[:len(fieldNames):len(fieldNames)], field.Name)
 
 		if !field.Anonymous {
 			var sep string
@@ -196,41 +468,125 @@ func (s *mapperSourceImpl) setMappings(typ reflect.Type, prefix string, v visite
 			}
 
 			name := tag
-			if tag == "" {
+			switch {
+			case tag != "":
+			case s.fieldPathMapperFn != nil:
+				name = s.fieldPathMapperFn(currentNames)
+			default:
 				name = s.fieldMapperFn(field.Name)
 			}
 
 			key = strings.Join([]string{key, name}, sep)
+			keyPath = append(keyPath[:len(keyPath):len(keyPath)], name)
 		}
 
-		currentIndex := append(position, i)
+		// Use full slice expressions so that sibling fields
+		// do not share the same backing arrays
+		currentIndex := append(position[:len(position):len(position)], i)
+		fieldInits := inits
 		fieldType := field.Type
 		var isPointer bool
 
+		// Multi-level pointers such as **T are mapped like *T,
+		// all the levels are allocated when the field is set
 		if fieldType.Kind() == reflect.Pointer {
-			inits = append(inits, currentIndex)
-			fieldType = fieldType.Elem()
+			fieldInits = append(inits[:len(inits):len(inits)], currentIndex)
 			isPointer = true
 		}
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		// Fields with the remain tag option capture the columns that are
+		// not claimed by other fields, so they are not mapped to a column
+		if hasTagOption(tagOpts[1:], "remain") {
+			*m = append(*m, mapinfo{
+				position:  currentIndex,
+				init:      fieldInits,
+				isPointer: isPointer,
+				remain:    true,
+			})
+			continue
+		}
+
+		// Fields with the json tag option are scanned as a single value
+		// and unmarshaled into the field
+		if hasTagOption(tagOpts[1:], "json") {
+			*m = append(*m, mapinfo{
+				name:      key,
+				path:      keyPath,
+				position:  currentIndex,
+				init:      fieldInits,
+				isPointer: isPointer,
+				isJSON:    true,
+			})
+			continue
+		}
+
+		// Fields with the split tag option are scanned as a string
+		// and split into the field
+		if sep := splitTagOption(field.Tag.Get(s.structTagKey)); sep != "" {
+			*m = append(*m, mapinfo{
+				name:      key,
+				path:      keyPath,
+				position:  currentIndex,
+				init:      fieldInits,
+				isPointer: isPointer,
+				split:     sep,
+			})
+			continue
+		}
+
+		// Slices (including []byte) are scanned directly as a single value
+		// since drivers handle arrays through their own scanners.
+		// Types defined from time.Time are also scanned directly
+		if fieldType.Kind() == reflect.Slice || isTimeType(fieldType) {
+			*m = append(*m, mapinfo{
+				name:      key,
+				path:      keyPath,
+				position:  currentIndex,
+				init:      fieldInits,
+				isPointer: isPointer,
+			})
+			continue
+		}
 
 		if fieldType.Kind() == reflect.Struct {
-			s.setMappings(field.Type, key, v.copy(), m, inits, currentIndex...)
+			start := len(*m)
+			s.setMappings(field.Type, key, keyPath, currentNames, v.copy(), m, fieldInits, currentIndex...)
+
+			// Embedded structs with the prefix tag option add the prefix
+			// to the names of their fields
+			if embedPrefix := prefixTagOption(tagOpts[1:]); field.Anonymous && embedPrefix != "" {
+				for i := start; i < len(*m); i++ {
+					info := &(*m)[i]
+					if len(info.path) <= len(keyPath) {
+						continue
+					}
+
+					info.path = append([]string(nil), info.path...)
+					info.path[len(keyPath)] = embedPrefix + info.path[len(keyPath)]
+					info.name = strings.Join(info.path, s.columnSeparator)
+				}
+			}
 			continue
 		}
 
 		*m = append(*m, mapinfo{
 			name:      key,
+			path:      keyPath,
 			position:  currentIndex,
-			init:      inits,
+			init:      fieldInits,
 			isPointer: isPointer,
 		})
 	}
 
 	// If it has no exported field (such as time.Time) then we attempt to
 	// directly scan into it
-	if !hasExported {
+	if !hasExported && !isScannable {
 		*m = append(*m, mapinfo{
 			name:      prefix,
+			path:      path,
 			position:  position,
 			init:      inits,
 			isPointer: isPointer,
@@ -238,21 +594,88 @@ func (s *mapperSourceImpl) setMappings(typ reflect.Type, prefix string, v visite
 	}
 }
 
-func filterColumns(ctx context.Context, c cols, m mapping, prefix string) (mapping, error) {
+func hasTagOption(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prefixTagOption returns the value of the prefix tag option
+func prefixTagOption(opts []string) string {
+	const opt = "prefix="
+
+	for _, o := range opts {
+		if strings.HasPrefix(o, opt) {
+			return o[len(opt):]
+		}
+	}
+
+	return ""
+}
+
+// splitTagOption returns the separator of the split tag option.
+// Since the separator can contain commas, everything after "split=" is used,
+// so it must be the last option
+func splitTagOption(tag string) string {
+	const opt = ",split="
+
+	i := strings.Index(tag, opt)
+	if i == -1 {
+		return ""
+	}
+
+	return tag[i+len(opt):]
+}
+
+func filterColumns(ctx context.Context, c cols, m mapping, opts mappingOptions) (mapping, error) {
+	prefix, transform := opts.structTagPrefix, opts.columnTransformer
+
+	aliases := opts.columnAliases
+	if ctxAliases, ok := ctx.Value(CtxKeyColumnAliases).(map[string]string); ok && len(ctxAliases) > 0 {
+		aliases = make(map[string]string, len(opts.columnAliases)+len(ctxAliases))
+		for col, alias := range opts.columnAliases {
+			aliases[col] = alias
+		}
+		for col, alias := range ctxAliases {
+			aliases[col] = alias
+		}
+
+		if err := checkAliases(aliases); err != nil {
+			return nil, err
+		}
+	}
+
 	// Filter the mapping so we only ask for the available columns
 	filtered := make(mapping, 0, len(c))
+	var bare []string
 	for _, name := range c {
 		key := name
-		if prefix != "" {
-			if !strings.HasPrefix(name, prefix) {
+		alias, isAlias := aliases[name]
+
+		switch {
+		case isAlias:
+			key = alias
+		case transform != nil:
+			key = transform(name)
+		}
+
+		if prefix != "" && !isAlias {
+			if !strings.HasPrefix(key, prefix) {
+				if opts.prefixFallthrough {
+					bare = append(bare, name)
+				}
 				continue
 			}
 
-			key = name[len(prefix):]
+			key = key[len(prefix):]
 		}
 
 		for _, info := range m {
-			if key == info.name {
+			if key == info.name && !info.remain {
 				info.name = name
 				filtered = append(filtered, info)
 				break
@@ -260,5 +683,93 @@ func filterColumns(ctx context.Context, c cols, m mapping, prefix string) (mappi
 		}
 	}
 
-	return filtered, nil
+	// Columns without the prefix are only used for fields
+	// that do not have a prefixed column
+	for _, name := range bare {
+		key := name
+		if transform != nil {
+			key = transform(name)
+		}
+
+		for _, info := range m {
+			if key != info.name || info.remain {
+				continue
+			}
+
+			if !filtered.hasAnyPosition([][]int{info.position}) {
+				info.name = name
+				filtered = append(filtered, info)
+			}
+			break
+		}
+	}
+
+	return withoutFallbacks(filtered), nil
+}
+
+// positionalFields binds the columns without a name to the field at the
+// same position among the fields of the mapping. Fields already matched by a
+// named column in filtered, after the aliases and the column transformer,
+// are not bound again. The fields are scanned by the index of the column
+func positionalFields(c cols, m mapping, filtered mapping, prefix string) mapping {
+	fields := make(mapping, 0, len(m))
+	for _, info := range m {
+		if !info.remain {
+			fields = append(fields, info)
+		}
+	}
+
+	var bound mapping
+	for i, name := range c {
+		if name != "" || i >= len(fields) {
+			continue
+		}
+
+		info := fields[i]
+		if filtered.hasAnyPosition([][]int{info.position}) {
+			continue
+		}
+
+		info.name = prefix + info.name
+		info.positional = true
+		info.index = i
+		bound = append(bound, info)
+	}
+
+	return bound
+}
+
+func hasColumn(c cols, name string) bool {
+	for _, col := range c {
+		if col == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withoutFallbacks removes the fields of scannable structs
+// whose whole column is also selected
+func withoutFallbacks(filtered mapping) mapping {
+	var hasFallbacks bool
+	for _, info := range filtered {
+		if len(info.fallbackOf) > 0 {
+			hasFallbacks = true
+			break
+		}
+	}
+
+	if !hasFallbacks {
+		return filtered
+	}
+
+	kept := make(mapping, 0, len(filtered))
+	for _, info := range filtered {
+		if !filtered.hasAnyPosition(info.fallbackOf) {
+			kept = append(kept, info)
+		}
+	}
+
+	return kept
 }
@@ -3,6 +3,7 @@ package stdscan
 import (
 	"context"
 	"database/sql"
+	"io"
 
 	"github.com/stephenafamo/scan"
 )
@@ -14,17 +15,82 @@ func One[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], sql string,
 	return scan.One(ctx, convert(exec), m, sql, args...)
 }
 
+// OneOrZero works like [One] but returns false instead of an error
+// when the query returns no rows
+func OneOrZero[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], sql string, args ...any) (T, bool, error) {
+	return scan.OneOrZero(ctx, convert(exec), m, sql, args...)
+}
+
 // All scans all rows from the query and returns a slice []T of all rows using a [StdQueryer] this is for use with *sql.DB, *sql.Tx or *sql.Conn or any similar implementations
 // that return *sql.Rows
 func All[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], sql string, args ...any) ([]T, error) {
 	return scan.All(ctx, convert(exec), m, sql, args...)
 }
 
+// AllMap scans the key and value columns of all rows from the query into a map.
+// If multiple rows have the same key, the last one is kept
+func AllMap[K comparable, V any](ctx context.Context, exec Queryer, keyCol, valCol string, sql string, args ...any) (map[K]V, error) {
+	return scan.AllMap[K, V](ctx, convert(exec), keyCol, valCol, sql, args...)
+}
+
+// AllSet scans the single column of every row from the query into a set.
+// Duplicate values are only kept once
+func AllSet[T comparable](ctx context.Context, exec Queryer, sql string, args ...any) (map[T]struct{}, error) {
+	return scan.AllSet[T](ctx, convert(exec), sql, args...)
+}
+
+// AllMulti runs a query that returns multiple result sets and maps the rows of
+// every result set with m. It returns one slice per result set
+func AllMulti[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], sql string, args ...any) ([][]T, error) {
+	return scan.AllMulti(ctx, convert(exec), m, sql, args...)
+}
+
+// ResultSets runs a query that returns multiple result sets and reads them in order
+// with the given [scan.ResultSet]s
+func ResultSets(ctx context.Context, exec Queryer, sets []scan.ResultSet, sql string, args ...any) error {
+	return scan.ResultSets(ctx, convert(exec), sets, sql, args...)
+}
+
+// Collect2 maps every row of the query with both mappers and returns the results in two slices
+func Collect2[A, B any](ctx context.Context, exec Queryer, ma scan.Mapper[A], mb scan.Mapper[B], sql string, args ...any) ([]A, []B, error) {
+	return scan.Collect2(ctx, convert(exec), ma, mb, sql, args...)
+}
+
+// Collect3 maps every row of the query with the three mappers and returns the results in three slices
+func Collect3[A, B, C any](ctx context.Context, exec Queryer, ma scan.Mapper[A], mb scan.Mapper[B], mc scan.Mapper[C], sql string, args ...any) ([]A, []B, []C, error) {
+	return scan.Collect3(ctx, convert(exec), ma, mb, mc, sql, args...)
+}
+
+// GroupByKeys groups the rows of the query into parents by the key columns and adds every child to its parent
+func GroupByKeys[P, C any](ctx context.Context, exec Queryer, parent scan.Mapper[P], child scan.Mapper[C], keyCols []string, add func(*P, C), sql string, args ...any) ([]P, error) {
+	return scan.GroupByKeys(ctx, convert(exec), parent, child, keyCols, add, sql, args...)
+}
+
+// AllWhile scans the rows from the query and returns them until predicate returns false for a row
+func AllWhile[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], sql string, predicate func(T) bool, args ...any) ([]T, error) {
+	return scan.AllWhile(ctx, convert(exec), m, sql, predicate, args...)
+}
+
+// AllTolerant scans all the rows that do not fail and returns the errors of the failed rows separately
+func AllTolerant[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], sql string, args ...any) ([]T, []error, error) {
+	return scan.AllTolerant(ctx, convert(exec), m, sql, args...)
+}
+
 // Cursor returns a cursor that works similar to *sql.Rows
 func Cursor[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], sql string, args ...any) (scan.ICursor[T], error) {
 	return scan.Cursor(ctx, convert(exec), m, sql, args...)
 }
 
+// CursorBuffered returns a cursor with all the rows in memory that can be iterated over multiple times
+func CursorBuffered[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], sql string, args ...any) (scan.BufferedCursor[T], error) {
+	return scan.CursorBuffered(ctx, convert(exec), m, sql, args...)
+}
+
+// CursorPrefetch returns a cursor that scans up to bufferSize rows ahead in a goroutine
+func CursorPrefetch[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], sql string, bufferSize int, args ...any) (scan.ICursor[T], error) {
+	return scan.CursorPrefetch(ctx, convert(exec), m, sql, bufferSize, args...)
+}
+
 // Each returns a function that can be used to iterate over the rows of a query
 // this function works with range-over-func so it is possible to do
 //
@@ -38,6 +104,34 @@ func Each[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], query stri
 	return scan.Each(ctx, convert(exec), m, query, args...)
 }
 
+// EachIndexed works like [Each] but also passes the zero-based index of the row
+func EachIndexed[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], query string, args ...any) func(func(int, T, error) bool) {
+	return scan.EachIndexed(ctx, convert(exec), m, query, args...)
+}
+
+// Batches returns a function that can be used to iterate over the rows of a query
+// in batches of up to size rows. The same slice is reused for every batch
+//
+//	for batch, err := range scan.Batches(ctx, exec, m, 100, query, args...) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // do something with batch
+//	}
+func Batches[T any](ctx context.Context, exec Queryer, m scan.Mapper[T], size int, query string, args ...any) func(func([]T, error) bool) {
+	return scan.Batches(ctx, convert(exec), m, size, query, args...)
+}
+
+// EncodeCSV runs the query and writes the results to w as CSV with a header row
+func EncodeCSV(ctx context.Context, exec Queryer, w io.Writer, query string, args ...any) error {
+	return scan.EncodeCSV(ctx, convert(exec), w, query, args...)
+}
+
+// EncodeCSVWith works like [EncodeCSV] but uses format to convert the values to strings
+func EncodeCSVWith(ctx context.Context, exec Queryer, w io.Writer, format func(any) string, query string, args ...any) error {
+	return scan.EncodeCSVWith(ctx, convert(exec), w, format, query, args...)
+}
+
 // A Queryer that returns the concrete type [*sql.Rows]
 type Queryer interface {
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
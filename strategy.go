@@ -0,0 +1,596 @@
+package scan
+
+import (
+	"database/sql"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// fieldStrategy is how a struct mapper scans a column and sets its field.
+// The strategy of every field is resolved once when the mapper is created
+type fieldStrategy struct {
+	// dest returns a new destination to scan the column into
+	dest func() reflect.Value
+
+	// set sets the field from the scanned destination.
+	// It is responsible for allocating the pointers to the field with [initField]
+	// and it returns a [MappingError] if the value cannot be set
+	set func(row, dest reflect.Value) error
+}
+
+// strategyResolver returns the strategy an option contributes for the field,
+// or nil if the option does not apply to it. ft is the type of the field.
+// It returns an error if the option is set for the column but cannot be used for the field
+type strategyResolver func(info mapinfo, ft reflect.Type) (*fieldStrategy, error)
+
+// strategyResolvers returns the resolvers of the options in order of precedence.
+// If more than one option applies to a field, only the first one is used
+func (o mappingOptions) strategyResolvers() []strategyResolver {
+	return []strategyResolver{
+		o.builderStrategy,
+		o.timeLayoutStrategy,
+		splitStrategy,
+		o.hstoreStrategy,
+		o.epochStrategy,
+		o.jsonStrategy,
+		o.bigNumberStrategy,
+		o.textStrategy,
+		o.factoryStrategy,
+		o.converterStrategy,
+		o.nullTypeStrategy,
+	}
+}
+
+// fieldStrategies resolves the strategy of every filtered field.
+// Fields that are nullable without being pointers, because of [WithNullAsZero] or [WithNilOnAllNull],
+// are scanned through a pointer if no option applies. Other fields without a strategy are nil
+// and should be scanned directly into the field.
+// Trimming with [WithTrimStringColumns] is applied on top of the strategy of the field
+func fieldStrategies(typ reflect.Type, filtered mapping, opts mappingOptions, nullable func(i int) bool) ([]*fieldStrategy, error) {
+	resolvers := opts.strategyResolvers()
+	strategies := make([]*fieldStrategy, len(filtered))
+
+	for i, info := range filtered {
+		ft := fieldOfType(typ, info.position).Type
+
+		for _, resolve := range resolvers {
+			strategy, err := resolve(info, ft)
+			if err != nil {
+				return nil, err
+			}
+
+			if strategy != nil {
+				strategies[i] = strategy
+				break
+			}
+		}
+
+		if strategies[i] == nil && nullable(i) && !info.isPointer {
+			strategies[i] = nullableStrategy(info, ft)
+		}
+
+		trimmed, err := opts.trimStrategy(info, ft, strategies[i])
+		if err != nil {
+			return nil, err
+		}
+		strategies[i] = trimmed
+	}
+
+	return strategies, nil
+}
+
+// initField allocates the pointers to the field and returns it
+func initField(row reflect.Value, info mapinfo) reflect.Value {
+	initPointers(row, info.init)
+	return fieldByIndex(row, info.position)
+}
+
+// fieldElem returns the type of the value of the field,
+// dereferencing every level of pointer fields
+func fieldElem(info mapinfo, ft reflect.Type) reflect.Type {
+	for info.isPointer && ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+
+	return ft
+}
+
+// valueOfField returns the value of the field, dereferencing every level of
+// pointer fields. The pointers should already be allocated with [initField]
+func valueOfField(info mapinfo, fv reflect.Value) reflect.Value {
+	for info.isPointer && fv.Kind() == reflect.Pointer {
+		fv = fv.Elem()
+	}
+
+	return fv
+}
+
+// newDest returns a function that creates destinations of the given type
+func newDest(typ reflect.Type) func() reflect.Value {
+	return func() reflect.Value {
+		return reflect.New(typ)
+	}
+}
+
+// nullString returns the string scanned into a *sql.NullString destination
+func nullString(dest reflect.Value) (string, bool) {
+	str := dest.Interface().(*sql.NullString)
+	return str.String, str.Valid
+}
+
+func directStrategy(info mapinfo, ft reflect.Type) *fieldStrategy {
+	return &fieldStrategy{
+		dest: newDest(ft),
+		set: func(row, dest reflect.Value) error {
+			initField(row, info).Set(dest.Elem())
+			return nil
+		},
+	}
+}
+
+func nullableStrategy(info mapinfo, ft reflect.Type) *fieldStrategy {
+	return &fieldStrategy{
+		dest: newDest(reflect.PtrTo(ft)),
+		set: func(row, dest reflect.Value) error {
+			fv := initField(row, info)
+			if dest.Elem().IsNil() {
+				return nil
+			}
+
+			fv.Set(dest.Elem().Elem())
+			return nil
+		},
+	}
+}
+
+func (o mappingOptions) builderStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	build := o.builders[info.name]
+	if build == nil {
+		return nil, nil
+	}
+
+	return &fieldStrategy{
+		dest: newDest(typeOf[any]()),
+		set: func(row, dest reflect.Value) error {
+			fv := initField(row, info)
+
+			built := build(fv.Interface(), dest.Elem().Interface())
+			if built == nil {
+				fv.Set(reflect.Zero(fv.Type()))
+			} else {
+				fv.Set(reflect.ValueOf(built))
+			}
+			return nil
+		},
+	}, nil
+}
+
+func (o mappingOptions) timeLayoutStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	layout, ok := o.timeLayouts[info.name]
+	if !ok {
+		return nil, nil
+	}
+
+	elem := fieldElem(info, ft)
+	if !isTimeType(elem) {
+		err := fmt.Errorf("time layout set for column %s but field type is %s", info.name, elem)
+		return nil, createError(err, "not a time field", info.name)
+	}
+
+	return &fieldStrategy{
+		dest: newDest(typeOf[sql.NullString]()),
+		set: func(row, dest reflect.Value) error {
+			str, valid := nullString(dest)
+			if !valid {
+				return nil
+			}
+
+			parsed, err := time.Parse(layout, str)
+			if err != nil {
+				return createError(err, "invalid time", info.name)
+			}
+
+			valueOfField(info, initField(row, info)).Set(reflect.ValueOf(parsed).Convert(elem))
+			return nil
+		},
+	}, nil
+}
+
+func splitStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	if info.split == "" {
+		return nil, nil
+	}
+
+	if ft != typeOf[[]string]() {
+		err := fmt.Errorf("split tag option set for column %s but field type is %s", info.name, ft)
+		return nil, createError(err, "not a string slice field", info.name)
+	}
+
+	return &fieldStrategy{
+		dest: newDest(typeOf[sql.NullString]()),
+		set: func(row, dest reflect.Value) error {
+			fv := initField(row, info)
+
+			str, valid := nullString(dest)
+			if !valid {
+				return nil
+			}
+
+			parts := []string{}
+			if str != "" {
+				parts = strings.Split(str, info.split)
+				for j := range parts {
+					parts[j] = strings.TrimSpace(parts[j])
+				}
+			}
+
+			fv.Set(reflect.ValueOf(parts))
+			return nil
+		},
+	}, nil
+}
+
+func (o mappingOptions) hstoreStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	if !o.hstoreColumns[info.name] {
+		return nil, nil
+	}
+
+	elem := fieldElem(info, ft)
+	if elem != typeOf[map[string]string]() && elem != typeOf[map[string]*string]() {
+		err := fmt.Errorf("hstore set for column %s but field type is %s", info.name, elem)
+		return nil, createError(err, "not an hstore field", info.name)
+	}
+
+	return &fieldStrategy{
+		dest: newDest(typeOf[sql.NullString]()),
+		set: func(row, dest reflect.Value) error {
+			str, valid := nullString(dest)
+			if !valid {
+				return nil
+			}
+
+			parsed, err := ParseHstore(str)
+			if err != nil {
+				return createError(err, "invalid hstore", info.name)
+			}
+
+			fv := valueOfField(info, initField(row, info))
+			if elem == typeOf[map[string]*string]() {
+				fv.Set(reflect.ValueOf(parsed))
+				return nil
+			}
+
+			strs := make(map[string]string, len(parsed))
+			for k, v := range parsed {
+				strs[k] = ""
+				if v != nil {
+					strs[k] = *v
+				}
+			}
+			fv.Set(reflect.ValueOf(strs))
+			return nil
+		},
+	}, nil
+}
+
+func (o mappingOptions) epochStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	unit, ok := o.epochColumns[info.name]
+	if !ok {
+		return nil, nil
+	}
+
+	elem := fieldElem(info, ft)
+	isTime := isTimeType(elem)
+
+	switch elem.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+	default:
+		if !isTime {
+			err := fmt.Errorf("epoch time set for column %s but field type is %s", info.name, elem)
+			return nil, createError(err, "not an epoch field", info.name)
+		}
+	}
+
+	destType := typeOf[sql.NullTime]()
+	if isTime {
+		destType = typeOf[sql.NullInt64]()
+	}
+
+	return &fieldStrategy{
+		dest: newDest(destType),
+		set: func(row, dest reflect.Value) error {
+			if !dest.Elem().FieldByName("Valid").Bool() {
+				return nil
+			}
+
+			var converted reflect.Value
+			switch scanned := dest.Interface().(type) {
+			case *sql.NullInt64:
+				converted = reflect.ValueOf(fromEpoch(scanned.Int64, unit)).Convert(elem)
+			case *sql.NullTime:
+				// The epoch may not fit in narrower fields, such as milliseconds in an int32
+				var err error
+				converted, err = convertNumber(reflect.ValueOf(toEpoch(scanned.Time, unit)), elem)
+				if err != nil {
+					return createError(err, "invalid epoch", info.name)
+				}
+			}
+
+			valueOfField(info, initField(row, info)).Set(converted)
+			return nil
+		},
+	}, nil
+}
+
+func (o mappingOptions) jsonStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	if !info.isJSON && !o.jsonColumns[info.name] {
+		return nil, nil
+	}
+
+	return &fieldStrategy{
+		dest: newDest(typeOf[[]byte]()),
+		set: func(row, dest reflect.Value) error {
+			if dest.Elem().IsNil() {
+				return nil
+			}
+
+			fv := initField(row, info)
+			target := fv.Addr()
+			if info.isPointer {
+				target = fv
+			}
+
+			if err := json.Unmarshal(dest.Elem().Bytes(), target.Interface()); err != nil {
+				return createError(err, "invalid json", info.name)
+			}
+			return nil
+		},
+	}, nil
+}
+
+func (o mappingOptions) bigNumberStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	elem := fieldElem(info, ft)
+	switch {
+	case !o.bigColumns.includes(info.name, isBigNumberType(elem)):
+		return nil, nil
+	case !isBigNumberType(elem):
+		err := fmt.Errorf("big number set for column %s but field type is %s", info.name, elem)
+		return nil, createError(err, "not a big number field", info.name)
+	}
+
+	return &fieldStrategy{
+		dest: newDest(typeOf[sql.NullString]()),
+		set: func(row, dest reflect.Value) error {
+			str, valid := nullString(dest)
+			if !valid {
+				return nil
+			}
+
+			target := valueOfField(info, initField(row, info)).Addr()
+			if err := parseBigNumber(target.Interface(), str); err != nil {
+				return createError(err, "invalid big number", info.name)
+			}
+			return nil
+		},
+	}, nil
+}
+
+func (o mappingOptions) textStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	if !o.textColumns.enabled {
+		return nil, nil
+	}
+
+	elem := fieldElem(info, ft)
+	ptr := reflect.PtrTo(elem)
+	isText := ptr.Implements(typeOf[encoding.TextUnmarshaler]())
+
+	// Without columns, types that can already be scanned are left alone
+	matches := isText && !ptr.Implements(typeOf[sql.Scanner]()) && !isTimeType(elem)
+
+	switch {
+	case !o.textColumns.includes(info.name, matches):
+		return nil, nil
+	case !isText:
+		err := fmt.Errorf("text unmarshaler set for column %s but field type is %s", info.name, elem)
+		return nil, createError(err, "not a text unmarshaler field", info.name)
+	}
+
+	return &fieldStrategy{
+		dest: newDest(typeOf[sql.NullString]()),
+		set: func(row, dest reflect.Value) error {
+			str, valid := nullString(dest)
+			if !valid {
+				return nil
+			}
+
+			target := valueOfField(info, initField(row, info)).Addr()
+			if err := target.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str)); err != nil {
+				return createError(err, "invalid text", info.name)
+			}
+			return nil
+		},
+	}, nil
+}
+
+func (o mappingOptions) factoryStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	if ft.Kind() != reflect.Interface {
+		return nil, nil
+	}
+
+	factory := o.factories[ft]
+	if factory == nil {
+		// Empty interfaces can hold any scanned value
+		if o.typeConverter == nil && ft.NumMethod() > 0 {
+			err := fmt.Errorf("no interface factory registered for column %s of type %s", info.name, ft)
+			return nil, createError(err, "no interface factory", info.name)
+		}
+		return nil, nil
+	}
+
+	return &fieldStrategy{
+		dest: factory,
+		set: func(row, dest reflect.Value) error {
+			fv := initField(row, info)
+
+			val := dest
+			if !val.Type().Implements(ft) {
+				val = val.Elem()
+			}
+
+			if !val.Type().Implements(ft) {
+				err := fmt.Errorf("interface factory for column %s returned %s which does not implement %s", info.name, dest.Type(), ft)
+				return createError(err, "invalid interface factory", info.name)
+			}
+
+			fv.Set(val)
+			return nil
+		},
+	}, nil
+}
+
+func (o mappingOptions) converterStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	if o.typeConverter == nil {
+		return nil, nil
+	}
+
+	converter := o.typeConverter
+	ctxConverter, _ := converter.(TypeConverterCtx)
+	return &fieldStrategy{
+		dest: func() reflect.Value {
+			if ctxConverter != nil {
+				return ctxConverter.TypeToDestinationCtx(info.name, ft)
+			}
+			return converter.TypeToDestination(ft)
+		},
+		set: func(row, dest reflect.Value) error {
+			fv := initField(row, info)
+			val := converter.ValueFromDestination(dest)
+
+			// The destination was created for the type of the field,
+			// so the converter can return the pointer itself
+			if info.isPointer && val.Type().AssignableTo(fv.Type()) {
+				fv.Set(val)
+				return nil
+			}
+
+			valueOfField(info, fv).Set(val)
+			return nil
+		},
+	}, nil
+}
+
+// nullTypeStrategy scans fields of basic types through the matching sql.Null* type
+// with [WithNullTypeCoercion]
+func (o mappingOptions) nullTypeStrategy(info mapinfo, ft reflect.Type) (*fieldStrategy, error) {
+	if !o.nullCoercion || info.isPointer {
+		return nil, nil
+	}
+
+	nt := nullType(ft)
+	if nt == nil {
+		return nil, nil
+	}
+
+	return &fieldStrategy{
+		dest: newDest(nt),
+		set: func(row, dest reflect.Value) error {
+			fv := initField(row, info)
+
+			null := dest.Elem()
+			if !null.FieldByName("Valid").Bool() {
+				return nil
+			}
+
+			val := null.Field(0)
+			if !isNumberKind(ft.Kind()) {
+				fv.Set(val.Convert(ft))
+				return nil
+			}
+
+			// The sql.Null* type can hold values that do not fit in the field
+			converted, err := convertNumber(val, ft)
+			if err != nil {
+				return createError(err, "invalid null type value", info.name)
+			}
+
+			fv.Set(converted)
+			return nil
+		},
+	}, nil
+}
+
+// nullType returns the sql.Null* type used to scan the type with [WithNullTypeCoercion]
+// or nil if there is none
+func nullType(ft reflect.Type) reflect.Type {
+	if reflect.PtrTo(ft).Implements(typeOf[sql.Scanner]()) {
+		return nil
+	}
+
+	if isTimeType(ft) {
+		return typeOf[sql.NullTime]()
+	}
+
+	switch ft.Kind() {
+	case reflect.String:
+		return typeOf[sql.NullString]()
+	case reflect.Bool:
+		return typeOf[sql.NullBool]()
+	case reflect.Int8, reflect.Int16:
+		return typeOf[sql.NullInt16]()
+	case reflect.Int32:
+		return typeOf[sql.NullInt32]()
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typeOf[sql.NullInt64]()
+	case reflect.Uint8:
+		return typeOf[sql.NullByte]()
+	case reflect.Float32, reflect.Float64:
+		return typeOf[sql.NullFloat64]()
+	}
+
+	return nil
+}
+
+// trimStrategy wraps the strategy of string fields to trim trailing whitespace
+// with [WithTrimStringColumns]. A nil strategy is the direct scan into the field
+func (o mappingOptions) trimStrategy(info mapinfo, ft reflect.Type, strategy *fieldStrategy) (*fieldStrategy, error) {
+	elem := fieldElem(info, ft)
+	switch {
+	case !o.trimColumns.includes(info.name, elem.Kind() == reflect.String):
+		return strategy, nil
+	case elem.Kind() != reflect.String:
+		err := fmt.Errorf("trimming set for column %s but field type is %s", info.name, elem)
+		return nil, createError(err, "not a string field", info.name)
+	}
+
+	if strategy == nil {
+		strategy = directStrategy(info, ft)
+	}
+
+	set := strategy.set
+	return &fieldStrategy{
+		dest: strategy.dest,
+		set: func(row, dest reflect.Value) error {
+			if err := set(row, dest); err != nil {
+				return err
+			}
+
+			// The pointers are nil if the field was not set
+			for _, v := range info.init {
+				if fieldByIndex(row, v).IsNil() {
+					return nil
+				}
+			}
+
+			fv := valueOfField(info, fieldByIndex(row, info.position))
+			if fv.Kind() == reflect.String {
+				fv.SetString(strings.TrimRightFunc(fv.String(), unicode.IsSpace))
+			}
+			return nil
+		},
+	}, nil
+}